@@ -3,7 +3,16 @@ package underscore
 import (
 	"github.com/markmontymark/asserts"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -63,16 +72,701 @@ func TestChainingWorksInSmallStages(t *testing.T) {
 				Value()), "[1 3]")
 }
 
-func TestReverseConcatUnshiftPopMap(t *testing.T) {
+func TestReverseConcatUnshiftSliceMap(t *testing.T) {
 	numbers1 := []T{1, 2, 3, 4, 5}
 	numbers2 := New(numbers1).
 		Chain().
 		Reverse().
 		Concat([]T{5, 5, 5}).
 		Unshift(17).
-		Pop().
+		Slice(0, -1).
 		Map(func(n, i, list T) T { return n.(int) * 2 }).
 		Value()
 	asserts.Equals(t, "can chain together array functions",
 		fmt.Sprint(numbers2), "[34 10 8 6 4 2 10 10]")
 }
+
+func TestArrayMethods(t *testing.T) {
+	list := New([]T{1, 2, 3, 4, 5}).Chain()
+	asserts.Equals(t, "pop gives the last element", fmt.Sprint(list.Pop().Value()), "5")
+	asserts.Equals(t, "shift gives the first element", fmt.Sprint(list.Shift().Value()), "1")
+	asserts.Nil(t, "pop of an empty array is nil", New([]T{}).Pop().Value())
+	asserts.Nil(t, "shift of an empty array is nil", New([]T{}).Shift().Value())
+	asserts.Equals(t, "push adds to the end", fmt.Sprint(list.Push(6, 7).Value()), "[1 2 3 4 5 6 7]")
+
+	asserts.Equals(t, "slice", fmt.Sprint(list.Slice(1, 3).Value()), "[2 3]")
+	asserts.Equals(t, "slice with a negative start", fmt.Sprint(list.Slice(-2).Value()), "[4 5]")
+	asserts.Equals(t, "slice with a negative end", fmt.Sprint(list.Slice(1, -1).Value()), "[2 3 4]")
+	asserts.Equals(t, "slice out of range", fmt.Sprint(list.Slice(4, 1).Value(), list.Slice(-10, 10).Value()), "[] [1 2 3 4 5]")
+
+	asserts.Equals(t, "splice replaces elements with items", fmt.Sprint(list.Splice(1, 2, "a").Value()), "[1 a 4 5]")
+	asserts.Equals(t, "splice from the end", fmt.Sprint(list.Splice(-2, 10).Value()), "[1 2 3]")
+	asserts.Equals(t, "splice with a negative count only inserts", fmt.Sprint(list.Splice(1, -1, "a", "b").Value()), "[1 a b 2 3 4 5]")
+	asserts.Equals(t, "splice past the end appends", fmt.Sprint(list.Splice(10, 1, "a").Value()), "[1 2 3 4 5 a]")
+
+	mixed := New([]T{10, 9, nil, 1, "b", "a"}).Chain()
+	asserts.Equals(t, "sort compares strings by default, nil last", fmt.Sprint(mixed.Sort().Value()), "[1 10 9 a b <nil>]")
+	byNumber := func(a, b T) int { return a.(int) - b.(int) }
+	asserts.Equals(t, "sort with a comparator",
+		fmt.Sprint(New([]T{10, 9, 1}).Sort(byNumber).Value()), "[1 9 10]")
+	asserts.Equals(t, "sort is stable",
+		fmt.Sprint(New([]T{"b1", "a1", "b2", "a2"}).Sort(func(a, b T) int { return int(a.(string)[0]) - int(b.(string)[0]) }).Value()),
+		"[a1 a2 b1 b2]")
+
+	asserts.Equals(t, "join with commas by default", fmt.Sprint(list.Join().Value()), "1,2,3,4,5")
+	asserts.Equals(t, "join with a separator, nil is empty, arrays are joined with commas",
+		fmt.Sprint(New([]T{1, nil, []T{2, 3}, "x"}).Join(" - ").Value()), "1 -  - 2,3 - x")
+
+	asserts.Equals(t, "fill", fmt.Sprint(list.Fill(0).Value()), "[0 0 0 0 0]")
+	asserts.Equals(t, "fill a range", fmt.Sprint(list.Fill(0, 1, -1).Value()), "[1 0 0 0 5]")
+
+	asserts.True(t, "includes", list.Includes(3).Value().(bool))
+	asserts.False(t, "includes from an index", list.Includes(1, 1).Value().(bool))
+	asserts.True(t, "includes from a negative index", list.Includes(5, -1).Value().(bool))
+	asserts.True(t, "includes NaN", New([]T{math.NaN()}).Includes(math.NaN()).Value().(bool))
+	inner := []T{1}
+	asserts.True(t, "includes the same array", New([]T{inner}).Includes(inner).Value().(bool))
+	asserts.False(t, "but not an equal one", New([]T{inner}).Includes([]T{1}).Value().(bool))
+
+	asserts.Equals(t, "flatMap flattens one level",
+		fmt.Sprint(list.FlatMap(func(v, i, l T) T { return []T{v, []T{v}} }).Slice(0, 4).Value()), "[1 [1] 2 [2]]")
+
+	asserts.Equals(t, "at", fmt.Sprint(list.At(0).Value(), list.At(-1).Value(), list.At(5).Value()), "1 5 <nil>")
+	asserts.Equals(t, "indexOf", fmt.Sprint(list.IndexOfFrom(3).Value()), "2")
+	asserts.Equals(t, "indexOf from an index", fmt.Sprint(New([]T{1, 2, 1}).IndexOfFrom(1, 1).Value()), "2")
+	asserts.Equals(t, "indexOf from a negative index", fmt.Sprint(list.IndexOfFrom(4, -1).Value(), list.IndexOfFrom(5, -1).Value()), "-1 4")
+	asserts.Equals(t, "indexOf never finds NaN", fmt.Sprint(New([]T{math.NaN()}).IndexOfFrom(math.NaN()).Value()), "-1")
+	asserts.Equals(t, "indexOf is strict", fmt.Sprint(list.IndexOfFrom("3").Value(), New([]T{inner}).IndexOfFrom(inner).Value()), "-1 0")
+	asserts.Equals(t, "none of them change the array", fmt.Sprint(list.Value()), "[1 2 3 4 5]")
+}
+
+// Package functions that have no chain method, and why
+var unchained = map[string]string{
+	"New":                "makes the *Underscore",
+	"Mixin":              "adds chain methods, see Call",
+	"NewOrderedMap":      "constructor",
+	"NewManualClock":     "constructor",
+	"WithClock":          "constructor, for timing functions on a given clock",
+	"NewIdGenerator":     "constructor",
+	"NewMemoizer":        "constructor, see Memoize",
+	"NewDebouncer":       "constructor, see Debounce",
+	"NewThrottler":       "constructor, see Throttle",
+	"NewRateLimiter":     "constructor, see RateLimit",
+	"FixedBackoff":       "builds a RetryPolicy's Backoff",
+	"ExponentialBackoff": "builds a RetryPolicy's Backoff",
+	"Jittered":           "builds a RetryPolicy's Backoff",
+	"IdentityEach":       "iterator, passed to other functions",
+	"IdentityIsTruthy":   "iterator, passed to other functions",
+	"IdentityComparator": "comparator, passed to other functions",
+	"IdentityMap":        "iterator, passed to other functions",
+	"IsArrayEach":        "iterator, passed to other functions",
+	"StreamFrom":         "stream functions work on channels",
+	"StreamCollect":      "stream functions work on channels",
+	"StreamMap":          "stream functions work on channels",
+	"StreamFilter":       "stream functions work on channels",
+	"StreamBatch":        "stream functions work on channels",
+	"StreamThrottle":     "stream functions work on channels",
+	"StreamDebounce":     "stream functions work on channels",
+	"StreamMerge":        "stream functions work on channels",
+	"StreamTee":          "stream functions work on channels",
+	"StreamWindow":       "stream functions work on channels",
+	"WindowCountBy":      "builds a StreamWindow aggregate",
+	"WindowGroupBy":      "builds a StreamWindow aggregate",
+	"WindowReduce":       "builds a StreamWindow aggregate",
+	"Parallel":           "task runners work on []Task with a context",
+	"Series":             "task runners work on []Task with a context",
+	"Waterfall":          "task runners work on []Task with a context",
+	"Race":               "task runners work on []Task with a context",
+	"FirstSuccess":       "task runners work on []Task with a context",
+	"AllSettled":         "task runners work on []Task with a context",
+}
+
+// Chain methods with no package function, and why
+var chainOnly = map[string]string{
+	"Value":       "unwraps the chain",
+	"String":      "fmt.Stringer",
+	"Call":        "calls functions added with Mixin",
+	"Reverse":     "Array method, which underscore.js only adds to the wrapper",
+	"Concat":      "Array method, which underscore.js only adds to the wrapper",
+	"Unshift":     "Array method, which underscore.js only adds to the wrapper",
+	"Shift":       "Array method, which underscore.js only adds to the wrapper",
+	"Pop":         "Array method, which underscore.js only adds to the wrapper",
+	"Push":        "Array method, which underscore.js only adds to the wrapper",
+	"Slice":       "Array method, which underscore.js only adds to the wrapper",
+	"Splice":      "Array method, which underscore.js only adds to the wrapper",
+	"Sort":        "Array method, which underscore.js only adds to the wrapper",
+	"Join":        "Array method, which underscore.js only adds to the wrapper",
+	"Fill":        "JS Array method, on the wrapper only",
+	"Includes":    "JS Array method, on the wrapper only, see Contains",
+	"FlatMap":     "JS Array method, on the wrapper only",
+	"At":          "JS Array method, on the wrapper only",
+	"IndexOfFrom": "JS Array indexOf, on the wrapper only, as IndexOf is underscore's",
+	"Chain":       "starts chaining the wrapped value rather than wrapping a new one",
+	"IsFinite":    "on the wrapper only, for float64s",
+	"IsNaN":       "on the wrapper only, for float64s",
+}
+
+// Chain methods whose signature doesn't follow the package function's, and why
+var chainedDifferently = map[string]string{
+	"Identity":      "variadic, so it can be passed as the iterator of Times and friends",
+	"Pipe":          "takes one func(T) T, see Mixin, where func Pipe takes funcs of any type",
+	"IsEmpty":       "returns the bool, and ignores its argument",
+	"All":           "ignores its first argument",
+	"Every":         "ignores its first argument",
+	"Times":         "returns the []T, the wrapped value is n",
+	"Random":        "returns the int, ignoring the wrapped value",
+	"RandomFloat64": "returns the float64, ignoring the wrapped value",
+	"GroupBy":       "returns the receiver, leaving the wrapped value as it was",
+	"IndexBy":       "returns the receiver, leaving the wrapped value as it was",
+	"CountBy":       "returns the receiver, leaving the wrapped value as it was",
+}
+
+// Internal type, a function or method's parameter and result types
+type parsedSignature struct {
+	params  []string
+	results []string
+}
+
+// Internal function, read the package's exported functions, including those assigned to
+// vars (aliases, GroupBy...), and the methods on *Underscore
+func parsePackage(t *testing.T) (funcs, methods map[string]parsedSignature) {
+	files, _ := filepath.Glob("*.go")
+	fset := token.NewFileSet()
+	parsed := make([]*ast.File, 0)
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed = append(parsed, f)
+	}
+	// named func types, ie eachlistiterator, are compared by what they name
+	named := make(map[string]ast.Expr)
+	for _, f := range parsed {
+		ast.Inspect(f, func(node ast.Node) bool {
+			if spec, ok := node.(*ast.TypeSpec); ok {
+				if _, ok := spec.Type.(*ast.FuncType); ok {
+					named[spec.Name.Name] = spec.Type
+				}
+			}
+			return true
+		})
+	}
+	signature := func(fn *ast.FuncType) parsedSignature {
+		return parsedSignature{fieldTypes(fn.Params, named), fieldTypes(fn.Results, named)}
+	}
+	funcs = make(map[string]parsedSignature)
+	methods = make(map[string]parsedSignature)
+	for _, f := range parsed {
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if !decl.Name.IsExported() {
+					continue
+				}
+				if decl.Recv == nil {
+					funcs[decl.Name.Name] = signature(decl.Type)
+				} else if typeString(decl.Recv.List[0].Type, named) == "*Underscore" {
+					methods[decl.Name.Name] = signature(decl.Type)
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					value, ok := spec.(*ast.ValueSpec)
+					if !ok || decl.Tok != token.VAR || !value.Names[0].IsExported() {
+						continue
+					}
+					if fn, ok := value.Type.(*ast.FuncType); ok {
+						funcs[value.Names[0].Name] = signature(fn)
+					} else if len(value.Values) == 1 {
+						if lit, ok := value.Values[0].(*ast.FuncLit); ok {
+							funcs[value.Names[0].Name] = signature(lit.Type)
+						}
+					}
+				}
+			}
+		}
+	}
+	return funcs, methods
+}
+
+// Internal function, the types in a parameter or result list, one per parameter
+func fieldTypes(fields *ast.FieldList, named map[string]ast.Expr) []string {
+	retval := make([]string, 0)
+	if fields == nil {
+		return retval
+	}
+	for _, field := range fields.List {
+		for i := 0; i < len(field.Names) || i == 0; i++ {
+			retval = append(retval, typeString(field.Type, named))
+		}
+	}
+	return retval
+}
+
+// Internal function, a type as a string, leaving out the parameter names of func types
+func typeString(expr ast.Expr, named map[string]ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if underlying, ok := named[expr.Name]; ok {
+			return typeString(underlying, named)
+		}
+	case *ast.Ellipsis:
+		return "..." + typeString(expr.Elt, named)
+	case *ast.ArrayType:
+		if expr.Len == nil {
+			return "[]" + typeString(expr.Elt, named)
+		}
+	case *ast.StarExpr:
+		return "*" + typeString(expr.X, named)
+	case *ast.MapType:
+		return "map[" + typeString(expr.Key, named) + "]" + typeString(expr.Value, named)
+	case *ast.FuncType:
+		return "func(" + strings.Join(fieldTypes(expr.Params, named), ", ") + ") (" +
+			strings.Join(fieldTypes(expr.Results, named), ", ") + ")"
+	}
+	return types.ExprString(expr)
+}
+
+// Internal function, whether a method's parameters are the function's, less the one the
+// wrapped value is passed as, or all of them when the wrapped value is the first of a variadic list
+// or the function takes none, ie Now
+func chainParams(fn, method []string) bool {
+	if len(fn) == 0 {
+		return len(method) == 0
+	}
+	for i := range fn {
+		without := append(append([]string{}, fn[:i]...), fn[i+1:]...)
+		if reflect.DeepEqual(without, method) {
+			return true
+		}
+	}
+	return len(fn) > 0 && strings.HasPrefix(fn[len(fn)-1], "...") && reflect.DeepEqual(fn, method)
+}
+
+// Replaces dev/func-vs-oop.pl: every package function has a chain method taking the same
+// arguments, less the wrapped value, and returning *Underscore, and the other way around
+func TestChainParity(t *testing.T) {
+	funcs, methods := parsePackage(t)
+	names := make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		method, ok := methods[name]
+		if _, skip := unchained[name]; skip {
+			if ok {
+				t.Errorf("%s has a chain method, take it out of unchained", name)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("func %s has no chain method, add func (this *Underscore) %s, or add it to unchained", name, name)
+			continue
+		}
+		if _, skip := chainedDifferently[name]; skip {
+			continue
+		}
+		if !reflect.DeepEqual(method.results, []string{"*Underscore"}) {
+			t.Errorf("chain method %s returns %v, not *Underscore", name, method.results)
+		}
+		if !chainParams(funcs[name].params, method.params) {
+			t.Errorf("chain method %s takes %v, func %s takes %v", name, method.params, name, funcs[name].params)
+		}
+	}
+	for name := range methods {
+		_, isFunc := funcs[name]
+		_, skip := chainOnly[name]
+		if !isFunc && !skip {
+			t.Errorf("chain method %s has no package func, add one, or add it to chainOnly", name)
+		}
+		if isFunc && skip {
+			t.Errorf("%s has a package func, take it out of chainOnly", name)
+		}
+	}
+}
+
+// The chain methods that can fail panic, with the package function's error
+func TestChainPanics(t *testing.T) {
+	failing := func(n int) (int, error) { return 0, fmt.Errorf("failed on %d", n) }
+	cases := []struct {
+		name string
+		call func()
+		want string
+	}{
+		{"Flow", func() { New(strings.ToUpper).Flow(func(n int) int { return n }) }, ""},
+		{"FlowRight", func() { New(strings.ToUpper).FlowRight(func(n int) int { return n }) }, ""},
+		{"Compose", func() { New(strings.ToUpper).Compose(func(n int) int { return n }) }, ""},
+		{"Template", func() { New("<%= (%>").Template() }, ""},
+		{"Call", func() { New(1).Call("neverMixedIn") }, `Call: no function was mixed in as "neverMixedIn"`},
+		{"Curry", func() { New(1).Curry() }, "Curry: 1 is not a function"},
+		{"Bind", func() { New(1).Bind("Missing") }, `Bind: int has no method "Missing"`},
+		{"Patch", func() { New(map[T]T{}).Patch([]PatchOp{{Op: "remove", Path: "/a"}}) }, ""},
+		{"Memoize", func() { New(1).Memoize() }, "Memoize: the wrapped value is a int, not a func(...T) T"},
+		{"Once", func() { New(failing).Once() }, "Once: the wrapped value is a func(int) (int, error), not a func(...T) T"},
+		{"Debounce", func() { New(1).Debounce(1) }, "Debounce: the wrapped value is a int, not a func() T"},
+		{"Retry", func() { New(nil).Retry(RetryPolicy{}) }, "Retry: the wrapped value is a <nil>, not a func(...T) (T, error)"},
+	}
+	for _, c := range cases {
+		func() {
+			defer func() {
+				message := fmt.Sprint(recover())
+				asserts.True(t, c.name+" panics", message != "<nil>")
+				if c.want != "" {
+					asserts.Equals(t, c.name+" panics with the error", message, c.want)
+				}
+			}()
+			c.call()
+		}()
+	}
+	piped, err := Pipe(1, failing)
+	asserts.True(t, "the package function returns the error instead", piped == nil && err != nil)
+}
+
+func TestChainedFunctions(t *testing.T) {
+	double := func(args ...T) T { return args[0].(int) * 2 }
+	calls := 0
+	counted := func(args ...T) T { calls++; return calls }
+
+	memoized := New(counted).Chain().Memoize().Value().(func(...T) T)
+	memoized(1)
+	memoized(1)
+	asserts.IntEquals(t, "chained Memoize caches", calls, 1)
+
+	once := New(counted).Chain().Once().Value().(func(...T) T)
+	asserts.Equals(t, "chained Once calls once", fmt.Sprint(once(), once()), "2 2")
+
+	add := func(args ...T) T { return args[0].(int) + args[1].(int) }
+	asserts.IntEquals(t, "chained Partial", New(add).Chain().Partial(10).Value().(func(...T) T)(5).(int), 15)
+
+	composed := New(double).Chain().Compose(func(args ...T) T { return args[0].(int) + 1 }).Value().(func(...T) T)
+	asserts.IntEquals(t, "chained Compose calls the wrapped function last", composed(3).(int), 8)
+
+	flowed := New(double).Chain().Flow(func(n int) int { return n + 1 }).Value().(func(...T) (T, error))
+	result, err := flowed(3)
+	asserts.Nil(t, "chained Flow", err)
+	asserts.IntEquals(t, "chained Flow calls the wrapped function first", result.(int), 7)
+
+	asserts.Equals(t, "chained Range starts from the wrapped int",
+		fmt.Sprint(New(2).Chain().Range(10, 3).Value()), "[2 5 8]")
+	asserts.Equals(t, "chained Uniq passes its iterator on",
+		fmt.Sprint(New([]T{1, 2, 3, 4}).Chain().Uniq(false, func(v, i, l T) T { return v.(int) % 2 }, func(a, b T) bool { return a == b }).Value()), "[1 2]")
+}
+
+// A chain method call for TestChainModes, made on New(wrapped), chained and not.
+// then, if given, turns the result into something to compare, ie by calling a returned function
+type chainCase struct {
+	method  string
+	wrapped T
+	call    func(*Underscore) *Underscore
+	then    func(T) T
+	want    string
+}
+
+func chainCases() []chainCase {
+	nums := []T{1, 2, 3, 4}
+	even := func(v, i, l T) bool { return v.(int)%2 == 0 }
+	positive := func(v, i, l T) bool { return v.(int) > 0 }
+	double := func(v, i, l T) T { return v.(int) * 2 }
+	sum := func(memo, v, i, l T) T { return memo.(int) + v.(int) }
+	concat := func(memo, v, i, l T) T { return memo.(string) + fmt.Sprint(v) }
+	lessThan := func(a, b T) bool { return a.(int) < b.(int) }
+	equal := func(a, b T) bool { return a == b }
+	ran := func(args ...T) T { return "ran" }
+	ranWithError := func(args ...T) (T, error) { return "ran", nil }
+	add := func(args ...T) T { return args[0].(int) + args[1].(int) }
+	twice := func(args ...T) T { return args[0].(int) * 2 }
+	inc := func(args ...T) T { return args[0].(int) + 1 }
+	call := func(args ...T) func(T) T {
+		return func(fn T) T { return fn.(func(...T) T)(args...) }
+	}
+	result := func(handle T) T { return handle.(*Delayed).Result() }
+	isFunc := func(fn T) T { return IsFunction(fn) || IsFunctionVariadic(fn) || fn != nil }
+	people := []T{map[T]T{"name": "moe", "age": 40}, map[T]T{"name": "curly", "age": 60}}
+	return []chainCase{
+		{"After", ran, func(u *Underscore) *Underscore { return u.After(1) }, call(), "ran"},
+		{"All", nums, func(u *Underscore) *Underscore { return u.All(nil, positive) }, nil, "true"},
+		{"Any", nums, func(u *Underscore) *Underscore { return u.Any(even) }, nil, "true"},
+		{"At", nums, func(u *Underscore) *Underscore { return u.At(-1) }, nil, "4"},
+		{"Before", ran, func(u *Underscore) *Underscore { return u.Before(2) }, call(), "ran"},
+		{"Bind", NewOrderedMap("a", 1), func(u *Underscore) *Underscore { return u.Bind("Len") }, call(), "1"},
+		{"BindAll", NewOrderedMap("a", 1), func(u *Underscore) *Underscore { return u.BindAll("Len") },
+			func(fns T) T { return fns.(map[string]func(...T) T)["Len"]() }, "1"},
+		{"Call", 3, func(u *Underscore) *Underscore { return u.Call("chainModesDouble") }, nil, "6"},
+		{"Chain", nums, func(u *Underscore) *Underscore { return u.Chain() }, nil, "[1 2 3 4]"},
+		{"Clone", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.Clone() }, nil, "map[a:1]"},
+		{"CloneDeep", map[T]T{"a": []T{1}}, func(u *Underscore) *Underscore { return u.CloneDeep() }, nil, "map[a:[1]]"},
+		{"Collect", nums, func(u *Underscore) *Underscore { return u.Collect(double) }, nil, "[2 4 6 8]"},
+		{"Compact", []T{0, 1, false, 2, "", 3}, func(u *Underscore) *Underscore { return u.Compact() }, nil, "[1 2 3]"},
+		{"Compose", twice, func(u *Underscore) *Underscore { return u.Compose(inc) }, call(3), "8"},
+		{"Concat", nums, func(u *Underscore) *Underscore { return u.Concat([]T{5}) }, nil, "[1 2 3 4 5]"},
+		{"Contains", nums, func(u *Underscore) *Underscore { return u.Contains(3) }, nil, "true"},
+		{"CountBy", nums, func(u *Underscore) *Underscore { return u.CountBy(nil) }, nil, "[1 2 3 4]"},
+		{"Curry", func(a, b int) int { return a + b }, func(u *Underscore) *Underscore { return u.Curry() },
+			func(fn T) T { return fn.(func(...T) T)(1).(func(...T) T)(2) }, "3"},
+		{"CurryN", add, func(u *Underscore) *Underscore { return u.CurryN(2) },
+			func(fn T) T { return fn.(func(...T) T)(1).(func(...T) T)(2) }, "3"},
+		{"Debounce", func() T { return "ran" }, func(u *Underscore) *Underscore { return u.Debounce(10) }, isFunc, "true"},
+		{"DebounceNano", func() T { return "ran" }, func(u *Underscore) *Underscore { return u.DebounceNano(10) }, isFunc, "true"},
+		{"Defaults", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.Defaults(map[T]T{"a": 2, "b": 2}) }, nil, "map[a:1 b:2]"},
+		{"Defer", ran, func(u *Underscore) *Underscore { return u.Defer() }, result, "ran"},
+		{"Delay", ran, func(u *Underscore) *Underscore { return u.Delay(0) }, result, "ran"},
+		{"DelayAndWait", ran, func(u *Underscore) *Underscore { return u.DelayAndWait(0) }, nil, "ran"},
+		{"DelayNano", ran, func(u *Underscore) *Underscore { return u.DelayNano(0) }, result, "ran"},
+		{"Detect", nums, func(u *Underscore) *Underscore { return u.Detect(even) }, nil, "2"},
+		{"Diff", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.Diff(map[T]T{"a": 2}) },
+			func(changes T) T { return len(changes.([]Change)) }, "1"},
+		{"Difference", nums, func(u *Underscore) *Underscore { return u.Difference(equal, []T{2, 3}) }, nil, "[1 4]"},
+		{"Drop", nums, func(u *Underscore) *Underscore { return u.Drop() }, nil, "[2 3 4]"},
+		{"Each", nums, func(u *Underscore) *Underscore { return u.Each(func(v, i, l T) bool { return false }) }, nil, "[1 2 3 4]"},
+		{"Escape", "<b>", func(u *Underscore) *Underscore { return u.Escape() }, nil, "&lt;b&gt;"},
+		{"Every", nums, func(u *Underscore) *Underscore { return u.Every(nil, even) }, nil, "false"},
+		{"Extend", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.Extend(map[T]T{"b": 2}) }, nil, "map[a:1 b:2]"},
+		{"Fill", nums, func(u *Underscore) *Underscore { return u.Fill(0, -2) }, nil, "[1 2 0 0]"},
+		{"Filter", nums, func(u *Underscore) *Underscore { return u.Filter(even) }, nil, "[2 4]"},
+		{"Find", nums, func(u *Underscore) *Underscore { return u.Find(even) }, nil, "2"},
+		{"FindWhere", people, func(u *Underscore) *Underscore { return u.FindWhere(map[T]T{"age": 60}) }, nil, "map[age:60 name:curly]"},
+		{"First", nums, func(u *Underscore) *Underscore { return u.First() }, nil, "1"},
+		{"FirstN", nums, func(u *Underscore) *Underscore { return u.FirstN(2) }, nil, "[1 2]"},
+		{"Flatten", []T{1, []T{2, []T{3}}}, func(u *Underscore) *Underscore { return u.Flatten() }, nil, "[1 2 3]"},
+		{"FlatMap", nums, func(u *Underscore) *Underscore { return u.FlatMap(func(v, i, l T) T { return []T{v, v} }) }, nil,
+			"[1 1 2 2 3 3 4 4]"},
+		{"FlattenMap", map[T]T{"a": map[T]T{"b": 1}}, func(u *Underscore) *Underscore { return u.FlattenMap() }, nil, "map[a.b:1]"},
+		{"Flow", twice, func(u *Underscore) *Underscore { return u.Flow(inc) },
+			func(fn T) T { v, _ := fn.(func(...T) (T, error))(3); return v }, "7"},
+		{"FlowRight", twice, func(u *Underscore) *Underscore { return u.FlowRight(inc) },
+			func(fn T) T { v, _ := fn.(func(...T) (T, error))(3); return v }, "8"},
+		{"FoldL", nums, func(u *Underscore) *Underscore { return u.FoldL(concat, "") }, nil, "1234"},
+		{"FoldR", nums, func(u *Underscore) *Underscore { return u.FoldR(concat, "") }, nil, "4321"},
+		{"Get", map[T]T{"a": []T{1, 2}}, func(u *Underscore) *Underscore { return u.Get("a.1") }, nil, "2"},
+		{"GroupBy", nums, func(u *Underscore) *Underscore { return u.GroupBy(nil) }, nil, "[1 2 3 4]"},
+		{"Has", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.Has("a") }, nil, "true"},
+		{"Head", nums, func(u *Underscore) *Underscore { return u.Head() }, nil, "1"},
+		{"HeadN", nums, func(u *Underscore) *Underscore { return u.HeadN(2) }, nil, "[1 2]"},
+		{"Includes", nums, func(u *Underscore) *Underscore { return u.Includes(4) }, nil, "true"},
+		{"Include", nums, func(u *Underscore) *Underscore { return u.Include(5) }, nil, "false"},
+		{"IndexBy", nums, func(u *Underscore) *Underscore { return u.IndexBy(nil) }, nil, "[1 2 3 4]"},
+		{"IndexOfFrom", nums, func(u *Underscore) *Underscore { return u.IndexOfFrom(3, -2) }, nil, "2"},
+		{"IndexOf", nums, func(u *Underscore) *Underscore { return u.IndexOf(3, lessThan) }, nil, "2"},
+		{"Initial", nums, func(u *Underscore) *Underscore { return u.Initial() }, nil, "[1 2 3]"},
+		{"Inject", nums, func(u *Underscore) *Underscore { return u.Inject(sum, 0) }, nil, "10"},
+		{"Intersection", []T{1, 2, 3}, func(u *Underscore) *Underscore { return u.Intersection(lessThan, []T{2, 3, 4}) }, nil, "[2 3]"},
+		{"Invert", map[T]T{"a": "b"}, func(u *Underscore) *Underscore { return u.Invert() }, nil, "map[b:a]"},
+		{"Invoke", nums, func(u *Underscore) *Underscore {
+			return u.Invoke(func(this T, args ...T) T { return this.(int) + args[0].(int) }, 10)
+		}, nil, "[11 12 13 14]"},
+		{"IsArray", nums, func(u *Underscore) *Underscore { return u.IsArray() }, nil, "true"},
+		{"IsArrayOfMaps", []map[T]T{}, func(u *Underscore) *Underscore { return u.IsArrayOfMaps() }, nil, "true"},
+		{"IsFinite", 1.5, func(u *Underscore) *Underscore { return u.IsFinite() }, nil, "true"},
+		{"IsFunction", double, func(u *Underscore) *Underscore { return u.IsFunction() }, nil, "true"},
+		{"IsFunctionVariadic", ran, func(u *Underscore) *Underscore { return u.IsFunctionVariadic() }, nil, "true"},
+		{"IsMap", map[T]T{}, func(u *Underscore) *Underscore { return u.IsMap() }, nil, "true"},
+		{"IsNaN", 1.5, func(u *Underscore) *Underscore { return u.IsNaN() }, nil, "false"},
+		{"IsOrderedMap", NewOrderedMap(), func(u *Underscore) *Underscore { return u.IsOrderedMap() }, nil, "true"},
+		{"IsString", "moe", func(u *Underscore) *Underscore { return u.IsString() }, nil, "true"},
+		{"IsStringArray", []string{"moe"}, func(u *Underscore) *Underscore { return u.IsStringArray() }, nil, "true"},
+		{"Join", nums, func(u *Underscore) *Underscore { return u.Join("-") }, nil, "1-2-3-4"},
+		{"JSONPatch", Diff(map[T]T{"a": 1}, map[T]T{"a": 2}), func(u *Underscore) *Underscore { return u.JSONPatch() },
+			func(ops T) T { return ops.([]PatchOp)[0].Op + " " + ops.([]PatchOp)[0].Path }, "replace /a"},
+		{"JSONPointer", []T{"a", 0}, func(u *Underscore) *Underscore { return u.JSONPointer() }, nil, "/a/0"},
+		{"Keys", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.Keys() }, nil, "[a]"},
+		{"Last", nums, func(u *Underscore) *Underscore { return u.Last(2) }, nil, "[3 4]"},
+		{"LastIndexOf", []T{1, 2, 1}, func(u *Underscore) *Underscore { return u.LastIndexOf(1) }, nil, "2"},
+		{"Limit", ran, func(u *Underscore) *Underscore { return u.Limit(1) }, call(), "ran"},
+		{"Map", nums, func(u *Underscore) *Underscore { return u.Map(double) }, nil, "[2 4 6 8]"},
+		{"Max", nums, func(u *Underscore) *Underscore { return u.Max(lessThan) }, nil, "4"},
+		{"MaxInt", []int{1, 5, 3}, func(u *Underscore) *Underscore { return u.MaxInt() }, nil, "5"},
+		{"Memoize", ran, func(u *Underscore) *Underscore { return u.Memoize() }, call(), "ran"},
+		{"Merge", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.Merge(map[T]T{"b": 2}) }, nil, "map[a:1 b:2]"},
+		{"MergeDeep", map[T]T{"a": map[T]T{"b": 1}}, func(u *Underscore) *Underscore {
+			return u.MergeDeep(map[T]T{"a": map[T]T{"c": 2}})
+		}, nil, "map[a:map[b:1 c:2]]"},
+		{"MergeDeepWith", map[T]T{"a": []T{1}}, func(u *Underscore) *Underscore {
+			return u.MergeDeepWith(MergeArraysReplace, map[T]T{"a": []T{2}})
+		}, nil, "map[a:[2]]"},
+		{"Min", nums, func(u *Underscore) *Underscore { return u.Min(lessThan) }, nil, "1"},
+		{"MinInt", []int{4, 1, 3}, func(u *Underscore) *Underscore { return u.MinInt() }, nil, "1"},
+		{"Now", nil, func(u *Underscore) *Underscore { return u.Now() }, func(n T) T { return n.(int64) > 0 }, "true"},
+		{"NowNano", nil, func(u *Underscore) *Underscore { return u.NowNano() }, func(n T) T { return n.(int64) > 0 }, "true"},
+		{"Object", []T{[]T{"a", 1}}, func(u *Underscore) *Underscore { return u.Object() }, nil, "map[a:1]"},
+		{"Omit", map[T]T{"a": 1, "b": 2}, func(u *Underscore) *Underscore { return u.Omit("a") }, nil, "map[b:2]"},
+		{"Once", ran, func(u *Underscore) *Underscore { return u.Once() }, call(), "ran"},
+		{"OnceWithError", ranWithError, func(u *Underscore) *Underscore { return u.OnceWithError() },
+			func(fn T) T { v, _ := fn.(func(...T) (T, error))(); return v }, "ran"},
+		{"Pairs", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.Pairs() }, nil, "[[a 1]]"},
+		{"Partial", add, func(u *Underscore) *Underscore { return u.Partial(10) }, call(5), "15"},
+		{"PartialRight", func(args ...T) T { return fmt.Sprint(args) }, func(u *Underscore) *Underscore { return u.PartialRight("b") },
+			call("a"), "[a b]"},
+		{"Partition", nums, func(u *Underscore) *Underscore { return u.Partition(func(v T) bool { return v.(int) > 2 }) }, nil, "[[3 4] [1 2]]"},
+		{"Patch", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.Patch([]PatchOp{{Op: "add", Path: "/b", Value: 2}}) },
+			nil, "map[a:1 b:2]"},
+		{"Pick", map[T]T{"a": 1, "b": 2}, func(u *Underscore) *Underscore { return u.Pick("a") }, nil, "map[a:1]"},
+		{"Pipe", 3, func(u *Underscore) *Underscore { return u.Pipe(func(v T) T { return v.(int) + 1 }) }, nil, "4"},
+		{"Pluck", people, func(u *Underscore) *Underscore { return u.Pluck("name") }, nil, "[moe curly]"},
+		{"Pop", nums, func(u *Underscore) *Underscore { return u.Pop() }, nil, "4"},
+		{"Push", nums, func(u *Underscore) *Underscore { return u.Push(5) }, nil, "[1 2 3 4 5]"},
+		{"Range", 0, func(u *Underscore) *Underscore { return u.Range(4) }, nil, "[0 1 2 3]"},
+		{"RateLimit", ran, func(u *Underscore) *Underscore { return u.RateLimit(0, 0) }, call(), "ran"},
+		{"Reduce", nums, func(u *Underscore) *Underscore { return u.Reduce(sum, 0) }, nil, "10"},
+		{"ReduceRight", nums, func(u *Underscore) *Underscore { return u.ReduceRight(concat, "") }, nil, "4321"},
+		{"Reject", nums, func(u *Underscore) *Underscore { return u.Reject(even) }, nil, "[1 3]"},
+		{"Rest", nums, func(u *Underscore) *Underscore { return u.Rest() }, nil, "[2 3 4]"},
+		{"Result", map[T]T{"name": "moe"}, func(u *Underscore) *Underscore { return u.Result("name") }, nil, "moe"},
+		{"Retry", ranWithError, func(u *Underscore) *Underscore { return u.Retry(RetryPolicy{}) },
+			func(fn T) T { v, _ := fn.(func(...T) (T, error))(); return v }, "ran"},
+		{"Reverse", nums, func(u *Underscore) *Underscore { return u.Reverse() }, nil, "[4 3 2 1]"},
+		{"Sample", []T{7}, func(u *Underscore) *Underscore { return u.Sample() }, nil, "7"},
+		{"Select", nums, func(u *Underscore) *Underscore { return u.Select(even) }, nil, "[2 4]"},
+		{"Set", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.Set("b", 2) }, nil, "map[a:1 b:2]"},
+		{"SetCopy", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.SetCopy("b", 2) }, nil, "map[a:1 b:2]"},
+		{"Shift", nums, func(u *Underscore) *Underscore { return u.Shift() }, nil, "1"},
+		{"Slice", nums, func(u *Underscore) *Underscore { return u.Slice(1, -1) }, nil, "[2 3]"},
+		{"Sort", []T{3, 1, 2}, func(u *Underscore) *Underscore { return u.Sort() }, nil, "[1 2 3]"},
+		{"Splice", nums, func(u *Underscore) *Underscore { return u.Splice(1, 2, 9) }, nil, "[1 9 4]"},
+		{"Shuffle", []T{7}, func(u *Underscore) *Underscore { return u.Shuffle() }, nil, "[7]"},
+		{"Size", nums, func(u *Underscore) *Underscore { return u.Size() }, nil, "4"},
+		{"Some", nums, func(u *Underscore) *Underscore { return u.Some(even) }, nil, "true"},
+		{"SortBy", []map[T]T{{"age": 40}, {"age": 60}}, func(u *Underscore) *Underscore {
+			return u.SortBy("age", func(a, b *map[T]T) bool { return (*a)["criteria"].(int) > (*b)["criteria"].(int) })
+		}, nil, "[map[age:60] map[age:40]]"},
+		{"SortBySorter", nums, func(u *Underscore) *Underscore {
+			return u.SortBySorter(func(n, i, l T) T { return -n.(int) }, func(a, b *map[T]T) bool {
+				return (*a)["criteria"].(int) < (*b)["criteria"].(int)
+			})
+		}, nil, "[4 3 2 1]"},
+		{"SortedIndex", []T{1, 2, 4}, func(u *Underscore) *Underscore { return u.SortedIndex(3, lessThan) }, nil, "2"},
+		{"Tail", nums, func(u *Underscore) *Underscore { return u.Tail() }, nil, "[2 3 4]"},
+		{"Take", nums, func(u *Underscore) *Underscore { return u.Take() }, nil, "1"},
+		{"TakeN", nums, func(u *Underscore) *Underscore { return u.TakeN(2) }, nil, "[1 2]"},
+		{"Tap", nums, func(u *Underscore) *Underscore { return u.Tap(func(args ...T) T { return nil }) }, nil, "[1 2 3 4]"},
+		{"Template", "hi <%= name %>", func(u *Underscore) *Underscore { return u.Template() },
+			func(fn T) T { text, _ := fn.(func(T) (string, error))(map[T]T{"name": "moe"}); return text }, "hi moe"},
+		{"Throttle", ran, func(u *Underscore) *Underscore { return u.Throttle(10) }, isFunc, "true"},
+		{"ThrottleNano", ran, func(u *Underscore) *Underscore { return u.ThrottleNano(10) }, isFunc, "true"},
+		{"ToArray", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.ToArray() }, nil, "[1]"},
+		{"Unescape", "&lt;b&gt;", func(u *Underscore) *Underscore { return u.Unescape() }, nil, "<b>"},
+		{"UnflattenMap", map[string]T{"a.b": 1}, func(u *Underscore) *Underscore { return u.UnflattenMap() }, nil, "map[a:map[b:1]]"},
+		{"Union", []T{1, 2}, func(u *Underscore) *Underscore { return u.Union([]T{2, 3}) }, nil, "[1 2 3]"},
+		{"Uniq", []T{1, 1, 2}, func(u *Underscore) *Underscore { return u.Uniq(false) }, nil, "[1 2]"},
+		{"Unique", []T{1, 1, 2}, func(u *Underscore) *Underscore { return u.Unique(false) }, nil, "[1 2]"},
+		{"UniqueId", "chain_", func(u *Underscore) *Underscore { return u.UniqueId() },
+			func(id T) T { return strings.HasPrefix(id.(string), "chain_") }, "true"},
+		{"Unset", map[T]T{"a": 1, "b": 2}, func(u *Underscore) *Underscore { return u.Unset("a") }, nil, "map[b:2]"},
+		{"UnsetCopy", map[T]T{"a": 1, "b": 2}, func(u *Underscore) *Underscore { return u.UnsetCopy("a") }, nil, "map[b:2]"},
+		{"Unshift", nums, func(u *Underscore) *Underscore { return u.Unshift(0) }, nil, "[0 1 2 3 4]"},
+		{"Update", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.Update("a", func(v T) T { return v.(int) + 1 }) }, nil, "map[a:2]"},
+		{"UpdateCopy", map[T]T{"a": 1}, func(u *Underscore) *Underscore {
+			return u.UpdateCopy("a", func(v T) T { return v.(int) + 1 })
+		}, nil, "map[a:2]"},
+		{"Values", map[T]T{"a": 1}, func(u *Underscore) *Underscore { return u.Values() }, nil, "[1]"},
+		{"Where", people, func(u *Underscore) *Underscore { return u.Where(map[T]T{"name": "moe"}) }, nil, "[map[age:40 name:moe]]"},
+		{"Without", nums, func(u *Underscore) *Underscore { return u.Without(2, 3) }, nil, "[1 4]"},
+		{"Wrap", twice, func(u *Underscore) *Underscore {
+			return u.Wrap(func(args ...T) T { return fmt.Sprint("<", args[0].(func(...T) T)(args[1:]...), ">") })
+		}, call(3), "<6>"},
+		{"Zip", []T{1, 2}, func(u *Underscore) *Underscore { return u.Zip([]T{"a", "b"}) }, nil, "[[1 a] [2 b]]"},
+	}
+}
+
+// Every chain method gives the same result whether chained or not, the result is
+// chained only if the receiver was, and the receiver is left as it was
+func TestChainModes(t *testing.T) {
+	Mixin("chainModesDouble", func(obj T, args ...T) T { return obj.(int) * 2 })
+	covered := make(map[string]bool)
+	for _, c := range chainCases() {
+		covered[c.method] = true
+		for _, chained := range []bool{false, true} {
+			receiver := New(c.wrapped)
+			if chained {
+				receiver = receiver.Chain()
+			}
+			before := fmt.Sprint(receiver.Value())
+			got := c.call(receiver)
+			if after := fmt.Sprint(receiver.Value()); after != before {
+				t.Errorf("%s: changed the wrapped value from %s to %s", c.method, before, after)
+			}
+			if receiver.ischained != chained {
+				t.Errorf("%s: changed the receiver's chaining", c.method)
+			}
+			if got.ischained != (chained || c.method == "Chain") {
+				t.Errorf("%s: chained %v, result chained %v", c.method, chained, got.ischained)
+			}
+			value := got.Value()
+			if c.then != nil {
+				value = c.then(value)
+			}
+			asserts.Equals(t, fmt.Sprintf("%s, chained %v", c.method, chained), fmt.Sprint(value), c.want)
+		}
+	}
+	typ := reflect.TypeOf(new(Underscore))
+	for i := 0; i < typ.NumMethod(); i++ {
+		method := typ.Method(i)
+		if method.Type.NumOut() == 1 && method.Type.Out(0) == typ && !covered[method.Name] {
+			t.Errorf("no chainCase for %s", method.Name)
+		}
+	}
+}
+
+func TestChainLeavesReceiver(t *testing.T) {
+	list := New([]T{1, 2, 3})
+	doubled := list.Map(func(v, i, l T) T { return v.(int) * 2 })
+	asserts.Equals(t, "unchained calls return the result", fmt.Sprint(doubled.Value()), "[2 4 6]")
+	asserts.Equals(t, "and leave the receiver as it was", fmt.Sprint(list.Value()), "[1 2 3]")
+	asserts.Equals(t, "results can be used again",
+		fmt.Sprint(doubled.Filter(func(v, i, l T) bool { return v.(int) > 2 }).Value()), "[4 6]")
+}
+
+func TestChainBranching(t *testing.T) {
+	list := make([]T, 3, 10)
+	copy(list, []T{1, 2, 3})
+	base := New(list).Chain()
+	a := base.Concat([]T{4})
+	b := base.Concat([]T{5})
+	asserts.Equals(t, "branches don't share appended elements", fmt.Sprint(a.Value(), b.Value()), "[1 2 3 4] [1 2 3 5]")
+
+	sliced := base.Slice(0, -1)
+	sliced.Concat([]T{9}).Push(8)
+	asserts.Equals(t, "appending to a slice leaves the original", fmt.Sprint(base.Value()), "[1 2 3]")
+
+	elems := make([]T, 1, 10)
+	c := base.Unshift(elems...)
+	d := base.Unshift(elems...).Concat([]T{7})
+	asserts.Equals(t, "unshift doesn't append to the elements given", fmt.Sprint(c.Value(), d.Value(), elems), "[<nil> 1 2 3] [<nil> 1 2 3 7] [<nil>]")
+
+	defaults := map[T]T{"a": 1}
+	extended := New(defaults).Chain().Extend(map[T]T{"b": 2}).Defaults(map[T]T{"c": 3})
+	asserts.Equals(t, "extend and defaults copy the map", fmt.Sprint(defaults, extended.Value()), "map[a:1] map[a:1 b:2 c:3]")
+	ordered := NewOrderedMap("a", 1)
+	New(ordered).Extend(map[T]T{"b": 2}).Defaults(map[T]T{"c": 3})
+	asserts.Equals(t, "and ordered maps", fmt.Sprint(ordered.Keys()), "[a]")
+
+	chained := New(list)
+	chained.Chain()
+	asserts.False(t, "Chain returns a new wrapper", chained.ischained)
+}
+
+// Every chain method can be called on a shared wrapper from several goroutines, run with -race
+func TestChainConcurrent(t *testing.T) {
+	Mixin("chainModesDouble", func(obj T, args ...T) T { return obj.(int) * 2 })
+	for _, c := range chainCases() {
+		receiver := New(c.wrapped).Chain()
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(c chainCase) {
+				defer wg.Done()
+				value := c.call(receiver).Value()
+				if c.then != nil {
+					value = c.then(value)
+				}
+				if got := fmt.Sprint(value); got != c.want {
+					t.Errorf("%s: got %s want %s", c.method, got, c.want)
+				}
+			}(c)
+		}
+		wg.Wait()
+	}
+}
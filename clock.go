@@ -0,0 +1,227 @@
+package underscore
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// A source of time, so the timing functions (Now, Delay, Debounce, Throttle, Memoize's TTL...)
+// can be driven by something other than the system clock, ie a ManualClock in tests.
+type Clock interface {
+	// The current time
+	Now() time.Time
+	// A Timer that sends the time on its channel after duration d, see time.NewTimer
+	NewTimer(d time.Duration) Timer
+	// Call f after duration d, see time.AfterFunc.  The returned Timer's channel is nil
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// A pending event made by a Clock
+type Timer interface {
+	// The channel the time is sent on when the timer fires
+	C() <-chan time.Time
+	// Prevent the timer firing, returns false if it already fired or was stopped
+	Stop() bool
+	// Make the timer fire after duration d instead, returns whether it was still pending
+	Reset(d time.Duration) bool
+}
+
+// The Clock backed by the time package
+var SystemClock Clock = systemClock{}
+
+// The Clock the timing functions use when they aren't given one.
+// It's read without a lock, so only set it before starting anything that uses it.
+// To test code built on the timing functions, give them a ManualClock instead, see WithClock.
+var DefaultClock Clock = SystemClock
+
+// The timing functions Delay, Defer, Debounce, Throttle, Now, NowNano, NewRateLimiter,
+// StreamBatch, StreamThrottle and StreamDebounce driven by a given Clock, ie
+// timing := WithClock(clock); timing.Delay(fn, 100); clock.Advance(100 * time.Millisecond)
+// Functions taking an options struct (NewDebouncer, NewMemoizer, Retry, StreamWindow...) take the Clock there instead.
+type Timing struct {
+	clock Clock
+}
+
+// The timing functions on clock, or on DefaultClock if it's nil
+func WithClock(clock Clock) Timing {
+	return Timing{clock}
+}
+
+// Internal function, the clock to use given an optional one
+func clockOrDefault(clock Clock) Clock {
+	if clock == nil {
+		return DefaultClock
+	}
+	return clock
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return systemTimer{time.AfterFunc(d, f)}
+}
+
+type systemTimer struct {
+	timer *time.Timer
+}
+
+func (this systemTimer) C() <-chan time.Time {
+	return this.timer.C
+}
+
+func (this systemTimer) Stop() bool {
+	return this.timer.Stop()
+}
+
+func (this systemTimer) Reset(d time.Duration) bool {
+	return this.timer.Reset(d)
+}
+
+// A Clock whose time only moves when told to, for deterministic tests of timing code, ie
+// clock := NewManualClock(); WithClock(clock).Delay(fn, 100); clock.Advance(100 * time.Millisecond)
+// Timers fire in time order during Advance and Set, AfterFunc functions are called
+// synchronously, before Advance returns.  Safe to use from multiple goroutines.
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+// Create a ManualClock, starting at the given time or, by default, the current time
+func NewManualClock(opt_start ...time.Time) *ManualClock {
+	this := new(ManualClock)
+	if len(opt_start) > 0 {
+		this.now = opt_start[0]
+	} else {
+		this.now = time.Now()
+	}
+	return this
+}
+
+func (this *ManualClock) Now() time.Time {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.now
+}
+
+func (this *ManualClock) NewTimer(d time.Duration) Timer {
+	return this.schedule(d, nil, make(chan time.Time, 1))
+}
+
+func (this *ManualClock) AfterFunc(d time.Duration, f func()) Timer {
+	return this.schedule(d, f, nil)
+}
+
+// Move the clock forward by d, firing every timer that comes due on the way
+func (this *ManualClock) Advance(d time.Duration) {
+	this.Set(this.Now().Add(d))
+}
+
+// Move the clock to t, firing every timer due by then.  The clock never moves backwards.
+func (this *ManualClock) Set(t time.Time) {
+	this.mu.Lock()
+	for {
+		next := this.nextTimer(t)
+		if next == nil {
+			break
+		}
+		next.pending = false
+		this.removeTimer(next)
+		if next.at.After(this.now) {
+			this.now = next.at
+		}
+		now := this.now
+		this.mu.Unlock()
+		if next.f != nil {
+			next.f()
+		} else {
+			select {
+			case next.c <- now:
+			default:
+			}
+		}
+		this.mu.Lock()
+	}
+	if t.After(this.now) {
+		this.now = t
+	}
+	this.mu.Unlock()
+}
+
+// The number of timers waiting to fire, useful to wait for a goroutine to have set its timer
+func (this *ManualClock) Pending() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return len(this.timers)
+}
+
+// Internal function, the earliest timer due by t, first scheduled first.  Called with the lock held
+func (this *ManualClock) nextTimer(t time.Time) *manualTimer {
+	sort.SliceStable(this.timers, func(i, j int) bool {
+		return this.timers[i].at.Before(this.timers[j].at)
+	})
+	if len(this.timers) == 0 || this.timers[0].at.After(t) {
+		return nil
+	}
+	return this.timers[0]
+}
+
+// Internal function to add a timer
+func (this *ManualClock) schedule(d time.Duration, f func(), c chan time.Time) *manualTimer {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	timer := &manualTimer{clock: this, at: this.now.Add(d), f: f, c: c, pending: true}
+	this.timers = append(this.timers, timer)
+	return timer
+}
+
+// Internal function.  Called with the lock held
+func (this *ManualClock) removeTimer(timer *manualTimer) {
+	for i, t := range this.timers {
+		if t == timer {
+			this.timers = append(this.timers[:i], this.timers[i+1:]...)
+			return
+		}
+	}
+}
+
+type manualTimer struct {
+	clock   *ManualClock
+	at      time.Time
+	f       func()
+	c       chan time.Time
+	pending bool
+}
+
+func (this *manualTimer) C() <-chan time.Time {
+	return this.c
+}
+
+func (this *manualTimer) Stop() bool {
+	this.clock.mu.Lock()
+	defer this.clock.mu.Unlock()
+	wasPending := this.pending
+	this.pending = false
+	this.clock.removeTimer(this)
+	return wasPending
+}
+
+func (this *manualTimer) Reset(d time.Duration) bool {
+	this.clock.mu.Lock()
+	defer this.clock.mu.Unlock()
+	wasPending := this.pending
+	this.clock.removeTimer(this)
+	this.pending = true
+	this.at = this.clock.now.Add(d)
+	this.clock.timers = append(this.clock.timers, this)
+	return wasPending
+}
@@ -1,10 +1,13 @@
 package underscore
 
 import (
+	"context"
 	"github.com/markmontymark/asserts"
 	"fmt"
 	"sort"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type IntSlice []int
@@ -86,6 +89,12 @@ func TestReduce(t *testing.T) {
 	}
 	asserts.IntEquals(t, "can reduce sum up an array", 6, v.(int))
 
+	memo := []T{0}
+	v, _ = Reduce([]T{1, 2}, func(sum T, num T, i T, list T) T { return sum.(int) + num.(int) }, memo...)
+	asserts.IntEquals(t, "reduce leaves the memo alone", 0, memo[0].(int))
+	v, _ = Reduce([]T{1, 2, 3}, func(sum T, num T, i T, list T) T { return sum.(int) + num.(int) })
+	asserts.IntEquals(t, "reduce starts from the first element without a memo", 6, v.(int))
+
 	v, err = Reduce(
 		[]T{1, 2, 3},
 		func(sum T, num T, i T, list T) T { return sum.(int) * num.(int) },
@@ -255,6 +264,11 @@ func TestInvoke(t *testing.T) {
 	})
 	asserts.Equals(t, "each array sorted", fmt.Sprint(result),
 		"[[1 5 7] [1 2 3]]")
+
+	sums := Invoke([]T{1, 2}, func(item T, args ...T) T {
+		return item.(int) + args[0].(int) + args[1].(int)
+	}, 10, 20)
+	asserts.Equals(t, "arguments are passed on to the method", fmt.Sprint(sums), "[31 32]")
 }
 
 func TestPluck(t *testing.T) {
@@ -404,10 +418,10 @@ func TestGroupBy(t *testing.T) {
 	})
 	asserts.Equals(t, "group ints ", "map[1:[1 3 5 1] 0:[2 4 6]]",
 		fmt.Sprint(data))
-	asserts.Equals(t, "group evens ", "[2 4 6]", fmt.Sprint(data[0]))
+	asserts.Equals(t, "group evens ", "[2 4 6]", fmt.Sprint(data.(map[T]T)[0]))
 
 	data2 := []T{"one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten"}
-	grouped := GroupBy(data2, func(obj T, key T, val T) T { return len(obj.(string)) })
+	grouped := GroupBy(data2, func(obj T, key T, val T) T { return len(obj.(string)) }).(map[T]T)
 
 	asserts.Equals(t, "grouping words of length 3",
 		fmt.Sprint(grouped[3]), "[one two six ten]")
@@ -423,6 +437,22 @@ func TestGroupBy(t *testing.T) {
 	asserts.Equals(t, "group by an object keys value", "map[1:[map[a:1 b:2] map[a:1 b:7 c:8]] 4:[map[a:4 c:5]]]", fmt.Sprint(grouped3))
 }
 
+func TestGroupByOrderedMap(t *testing.T) {
+	numbers := NewOrderedMap("a", 1, "b", 2, "c", 3, "d", 4, "e", 5, "f", 6, "g", 1)
+	data := GroupBy(numbers, func(obj, key, val T) T {
+		return obj.(int) % 2
+	})
+	asserts.Equals(t, "groups an OrderedMap's values into an OrderedMap", fmt.Sprint(data), "map[1:[1 3 5 1] 0:[2 4 6]]")
+	asserts.Equals(t, "keys in first-seen order", fmt.Sprint(data.(*OrderedMap).Keys()), "[1 0]")
+
+	words := NewOrderedMap("x", "three", "y", "one", "z", "four")
+	counted := CountBy(words, func(obj, key, val T) T { return len(obj.(string)) })
+	asserts.Equals(t, "counts an OrderedMap's values in order", fmt.Sprint(counted), "map[5:1 3:1 4:1]")
+
+	indexed := IndexBy(NewOrderedMap(1, map[T]T{"id": "b"}, 2, map[T]T{"id": "a"}), "id")
+	asserts.Equals(t, "indexes in first-seen order", fmt.Sprint(indexed.(*OrderedMap).Keys()), "[b a]")
+}
+
 //TODO add more 'indexBy' tests from collections.js
 func TestIndexBy(t *testing.T) {
 	data := []map[T]T{{"a": 1, "b": 2}, {"b": 3}, {"a": 4, "c": 5}, {"a": 1, "b": 7, "c": 8}}
@@ -495,14 +525,17 @@ func TestSample(t *testing.T) {
 			return Contains(all_sampled2.([]T), val)
 		}))
 
-	/*
-	   ok(_.contains(numbers, _.sample(numbers)), 'sampling a single element returns something from the array');
-	   strictEqual(_.sample([]), undefined, 'sampling empty array with no number returns undefined');
-	   notStrictEqual(_.sample([], 5), [], 'sampling empty array with a number returns an empty array');
-	   notStrictEqual(_.sample([1, 2, 3], 0), [], 'sampling an array with 0 picks returns an empty array');
-	   deepEqual(_.sample([1, 2], -1), [], 'sampling a negative number of picks returns an empty array');
-	   ok(_.contains([1, 2, 3], _.sample({a: 1, b: 2, c: 3})), 'sample one value from an object');
-	*/
+	for i := 0; i < 100; i++ {
+		asserts.True(t, "sampling a single element returns something from the array",
+			Contains(numbers, Sample(numbers)))
+		asserts.True(t, "sample one value from an object",
+			Contains([]T{1, 2, 3}, Sample(map[T]T{"a": 1, "b": 2, "c": 3})))
+	}
+	asserts.Nil(t, "sampling empty array with no number returns nil", Sample([]T{}))
+	asserts.Nil(t, "sampling empty object returns nil", Sample(map[T]T{}))
+	asserts.IntEquals(t, "sampling empty array with a number returns an empty array", len(Sample([]T{}, 5).([]T)), 0)
+	asserts.IntEquals(t, "sampling an array with 0 picks returns an empty array", len(Sample([]T{1, 2, 3}, 0).([]T)), 0)
+	asserts.IntEquals(t, "sampling a negative number of picks returns an empty array", len(Sample([]T{1, 2}, -1).([]T)), 0)
 }
 
 func TestToArray(t *testing.T) {
@@ -529,3 +562,267 @@ func TestSize(t *testing.T) {
 	data20 := Size([]T{})
 	asserts.IntEquals(t, "size of an empty list ", 0, data20)
 }
+
+// A ManualClock that reports each timer made, so a test can wait for a stream's goroutine to set its timer
+type signallingClock struct {
+	*ManualClock
+	timers chan struct{}
+}
+
+func newSignallingClock() signallingClock {
+	return signallingClock{NewManualClock(), make(chan struct{})}
+}
+
+func (this signallingClock) NewTimer(d time.Duration) Timer {
+	timer := this.ManualClock.NewTimer(d)
+	this.timers <- struct{}{}
+	return timer
+}
+
+func TestStreamMapFilter(t *testing.T) {
+	ctx := context.Background()
+	isOdd := func(elem, i, list T) bool { return elem.(int)%2 != 0 }
+	triple := func(elem, i, list T) T { return elem.(int) * 3 }
+	list := []T{1, 2, 3, 4, 5}
+	asserts.Equals(t, "filter and map a stream with the same iterators as a list",
+		fmt.Sprint(StreamCollect(ctx, StreamMap(ctx, StreamFilter(ctx, StreamFrom(ctx, list), isOdd), triple))),
+		fmt.Sprint(Map(Filter(list, isOdd), triple)))
+	indexes := StreamCollect(ctx, StreamMap(ctx, StreamFrom(ctx, []T{"a", "b"}), func(elem, i, list T) T { return i }))
+	asserts.Equals(t, "iterators get the index in the stream", fmt.Sprint(indexes), "[0 1]")
+
+	var produced int64
+	source := make(chan T)
+	go func() {
+		defer close(source)
+		for i := 0; i < 100; i++ {
+			source <- i
+			atomic.AddInt64(&produced, 1)
+		}
+	}()
+	slow := StreamMap(ctx, source, triple)
+	<-slow
+	<-slow
+	time.Sleep(5 * time.Millisecond)
+	asserts.True(t, "a slow reader holds up the source", atomic.LoadInt64(&produced) < 5)
+
+	ctx, cancel := context.WithCancel(ctx)
+	endless := make(chan T)
+	mapped := StreamMap(ctx, endless, triple)
+	cancel()
+	_, open := <-mapped
+	asserts.False(t, "cancelling the context closes the stream", open)
+}
+
+func TestStreamBatch(t *testing.T) {
+	ctx := context.Background()
+	clock := newSignallingClock()
+	in := make(chan T)
+	out := WithClock(clock).StreamBatch(ctx, in, 3, 100*time.Millisecond)
+	in <- 1
+	<-clock.timers
+	in <- 2
+	in <- 3
+	asserts.Equals(t, "a full batch is passed on", fmt.Sprint(<-out), "[1 2 3]")
+	in <- 4
+	<-clock.timers
+	clock.Advance(100 * time.Millisecond)
+	asserts.Equals(t, "a partial batch is passed on after maxWait", fmt.Sprint(<-out), "[4]")
+	in <- 5
+	<-clock.timers
+	close(in)
+	asserts.Equals(t, "the last batch is passed on when the input closes", fmt.Sprint(<-out), "[5]")
+	_, open := <-out
+	asserts.False(t, "then the output closes", open)
+}
+
+func TestStreamThrottle(t *testing.T) {
+	ctx := context.Background()
+	clock := newSignallingClock()
+	in := make(chan T)
+	out := WithClock(clock).StreamThrottle(ctx, in, 100*time.Millisecond)
+	in <- 1
+	asserts.IntEquals(t, "the first element is passed straight on", (<-out).(int), 1)
+	<-clock.timers
+	in <- 2
+	in <- 3
+	clock.Advance(100 * time.Millisecond)
+	asserts.IntEquals(t, "then the latest once the wait is over", (<-out).(int), 3)
+	<-clock.timers
+	clock.Advance(100 * time.Millisecond)
+	in <- 4
+	asserts.IntEquals(t, "after a quiet wait, the next is passed straight on", (<-out).(int), 4)
+	<-clock.timers
+	close(in)
+	_, open := <-out
+	asserts.False(t, "the output closes with the input", open)
+}
+
+func TestStreamDebounce(t *testing.T) {
+	ctx := context.Background()
+	clock := newSignallingClock()
+	in := make(chan T)
+	out := WithClock(clock).StreamDebounce(ctx, in, 100*time.Millisecond)
+	in <- "a"
+	<-clock.timers
+	clock.Advance(50 * time.Millisecond)
+	in <- "b"
+	<-clock.timers
+	clock.Advance(99 * time.Millisecond)
+	select {
+	case value := <-out:
+		t.Errorf("passed on %v too soon", value)
+	default:
+	}
+	clock.Advance(time.Millisecond)
+	asserts.Equals(t, "the last element is passed on after a quiet wait", (<-out).(string), "b")
+	in <- "c"
+	<-clock.timers
+	close(in)
+	asserts.Equals(t, "a pending element is passed on when the input closes", (<-out).(string), "c")
+	_, open := <-out
+	asserts.False(t, "then the output closes", open)
+}
+
+func TestStreamMergeTee(t *testing.T) {
+	ctx := context.Background()
+	merged := StreamCollect(ctx, StreamMerge(ctx, StreamFrom(ctx, []T{1, 3, 5}), StreamFrom(ctx, []T{2, 4})))
+	sort.Slice(merged, func(i, j int) bool { return merged[i].(int) < merged[j].(int) })
+	asserts.Equals(t, "merge gets every element of every stream", fmt.Sprint(merged), "[1 2 3 4 5]")
+
+	tees := StreamTee(ctx, StreamFrom(ctx, []T{"x", "y", "z"}), 2)
+	second := make(chan []T)
+	go func() { second <- StreamCollect(ctx, tees[1]) }()
+	asserts.Equals(t, "each tee gets every element", fmt.Sprint(StreamCollect(ctx, tees[0])), "[x y z]")
+	asserts.Equals(t, "each tee gets every element, 2", fmt.Sprint(<-second), "[x y z]")
+}
+
+// Internal function for the window tests, a list of elements at the given seconds past the minute
+func stampedAt(base time.Time, seconds ...int) []T {
+	retval := make([]T, len(seconds))
+	for i, second := range seconds {
+		retval[i] = Timestamped{Time: base.Add(time.Duration(second) * time.Second), Value: second}
+	}
+	return retval
+}
+
+// Internal function for the window tests, each window's offsets from base and result
+func describeWindows(base time.Time, windows []Window) string {
+	described := make([]string, len(windows))
+	for i, window := range windows {
+		described[i] = fmt.Sprintf("%v-%v:%v", window.Start.Sub(base).Seconds(), window.End.Sub(base).Seconds(), window.Result)
+	}
+	return fmt.Sprint(described)
+}
+
+// Internal function for the window tests, read windows until the output closes
+func collectWindows(out <-chan Window) []Window {
+	windows := make([]Window, 0)
+	for window := range out {
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+func TestStreamWindowTumbling(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	in := make(chan T)
+	out := StreamWindow(ctx, in, WindowOptions{Size: 10 * time.Second}, nil)
+	go func() {
+		for _, elem := range stampedAt(base, 1, 4, 12) {
+			in <- elem
+		}
+	}()
+	window := <-out
+	asserts.Equals(t, "a window is passed on once an element passes its end",
+		describeWindows(base, []Window{window}), "[0-10:[1 4]]")
+	asserts.IntEquals(t, "with its count", window.Count, 2)
+	close(in)
+	asserts.Equals(t, "open windows are passed on when the input closes",
+		describeWindows(base, collectWindows(out)), "[10-20:[12]]")
+
+	sum := WindowReduce(func(memo, value, i, list T) T { return memo.(int) + value.(int) }, 0)
+	windows := collectWindows(StreamWindow(ctx, StreamFrom(ctx, stampedAt(base, 1, 4, 12, 25)), WindowOptions{Size: 10 * time.Second}, sum))
+	asserts.Equals(t, "windows are reduced, empty windows are skipped", describeWindows(base, windows), "[0-10:5 10-20:12 20-30:25]")
+
+	tally := WindowReduce(func(memo, value, i, list T) T {
+		memo.(map[T]T)[value.(int)%2 == 0] = true
+		return memo
+	}, map[T]T{})
+	windows = collectWindows(StreamWindow(ctx, StreamFrom(ctx, stampedAt(base, 1, 12)), WindowOptions{Size: 10 * time.Second}, tally))
+	asserts.Equals(t, "each window reduces into its own copy of the memo", describeWindows(base, windows), "[0-10:map[false:true] 10-20:map[true:true]]")
+
+	counts := collectWindows(StreamWindow(ctx, StreamFrom(ctx, stampedAt(base, 1, 2, 3, 11)), WindowOptions{Size: 10 * time.Second},
+		WindowCountBy(func(value, i, list T) T { return value.(int)%2 == 0 })))
+	asserts.Equals(t, "windows are counted by", fmt.Sprint(counts[0].Result.(map[T]T)[true], counts[0].Result.(map[T]T)[false]), "1 2")
+	groups := collectWindows(StreamWindow(ctx, StreamFrom(ctx, stampedAt(base, 1, 2, 3)), WindowOptions{Size: 10 * time.Second},
+		WindowGroupBy(func(value, i, list T) T { return value.(int)%2 == 0 })))
+	asserts.Equals(t, "windows are grouped by", fmt.Sprint(groups[0].Result.(map[T]T)[false]), "[1 3]")
+}
+
+func TestStreamWindowSliding(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	windows := collectWindows(StreamWindow(ctx, StreamFrom(ctx, stampedAt(base, 1, 6, 12)),
+		WindowOptions{Size: 10 * time.Second, Slide: 5 * time.Second}, nil))
+	asserts.Equals(t, "elements fall in every window covering them, in order of the windows' ends",
+		describeWindows(base, windows), "[-5-5:[1] 0-10:[1 6] 5-15:[6 12] 10-20:[12]]")
+
+	late := make([]T, 0)
+	windows = collectWindows(StreamWindow(ctx, StreamFrom(ctx, stampedAt(base, 1, 7, 12, 18, 21)),
+		WindowOptions{Size: 5 * time.Second, Slide: 10 * time.Second, OnLate: func(elem T) { late = append(late, elem) }}, nil))
+	asserts.Equals(t, "windows spaced apart drop the elements between them",
+		describeWindows(base, windows), "[0-5:[1] 10-15:[12] 20-25:[21]]")
+	asserts.IntEquals(t, "which aren't late", len(late), 0)
+
+	func() {
+		defer func() {
+			asserts.Equals(t, "options without a Size or Gap panic", fmt.Sprint(recover()),
+				"StreamWindow: WindowOptions needs a Size or Gap above 0")
+		}()
+		StreamWindow(ctx, nil, WindowOptions{}, nil)
+	}()
+}
+
+func TestStreamWindowSession(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	windows := collectWindows(StreamWindow(ctx, StreamFrom(ctx, stampedAt(base, 1, 3, 20, 4, 22, 40)),
+		WindowOptions{Gap: 5 * time.Second, AllowedLateness: 20 * time.Second}, nil))
+	asserts.Equals(t, "sessions run until a gap without elements, late elements within the lateness join them",
+		describeWindows(base, windows), "[1-9:[1 3 4] 20-27:[20 22] 40-45:[40]]")
+
+	windows = collectWindows(StreamWindow(ctx, StreamFrom(ctx, stampedAt(base, 1, 9, 5)),
+		WindowOptions{Gap: 5 * time.Second, AllowedLateness: 5 * time.Second}, nil))
+	asserts.Equals(t, "an element between sessions merges them",
+		describeWindows(base, windows), "[1-14:[1 9 5]]")
+}
+
+func TestStreamWindowLate(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	late := make([]T, 0)
+	options := WindowOptions{Size: 10 * time.Second, OnLate: func(elem T) { late = append(late, elem.(Timestamped).Value) }}
+	windows := collectWindows(StreamWindow(ctx, StreamFrom(ctx, stampedAt(base, 1, 12, 3, 14)), options, nil))
+	asserts.Equals(t, "elements for closed windows are dropped", describeWindows(base, windows), "[0-10:[1] 10-20:[12 14]]")
+	asserts.Equals(t, "and passed to OnLate", fmt.Sprint(late), "[3]")
+
+	options.AllowedLateness = 5 * time.Second
+	late = late[:0]
+	windows = collectWindows(StreamWindow(ctx, StreamFrom(ctx, stampedAt(base, 1, 12, 3, 16, 8)), options, nil))
+	asserts.Equals(t, "allowed lateness holds windows open", describeWindows(base, windows), "[0-10:[1 3] 10-20:[12 16]]")
+	asserts.Equals(t, "until the watermark passes them", fmt.Sprint(late), "[8]")
+
+	options = WindowOptions{Size: 10 * time.Second, Time: func(elem T) time.Time { return base.Add(time.Duration(elem.(int)) * time.Second) }}
+	windows = collectWindows(StreamWindow(ctx, StreamFrom(ctx, []T{2, 5, 11}), options, nil))
+	asserts.Equals(t, "times can be read from the elements", describeWindows(base, windows), "[0-10:[2 5] 10-20:[11]]")
+
+	clock := NewManualClock(base)
+	in := make(chan T)
+	out := StreamWindow(ctx, in, WindowOptions{Size: 10 * time.Second, Clock: clock}, nil)
+	in <- 1
+	clock.Advance(12 * time.Second)
+	in <- 2
+	close(in)
+	asserts.Equals(t, "or stamped by the Clock given", describeWindows(base, collectWindows(out)), "[0-10:[1] 10-20:[2]]")
+}
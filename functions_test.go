@@ -1,10 +1,8 @@
 package underscore
 
-// TODO, for the new debounce, throttle, delay tests, I'm using a DelayAndWait func added to underscore, 
-//  but perhaps it would be better to append all delay channels to one channel slice, and block in one place
-// in this file, instead of blocking in the DelayAndWait call.  
-
 import (
+	"context"
+	"errors"
 	"github.com/markmontymark/asserts"
 	"fmt"
 	"sync"
@@ -15,8 +13,6 @@ import (
 
 var fib func(...T) T
 
-var globalWg sync.WaitGroup
-
 func init() {
 	fib = func(n ...T) T {
 		if n[0].(int) < 2 {
@@ -26,7 +22,33 @@ func init() {
 	}
 }
 
-// XXX: missing bind
+type bindCounter struct {
+	count int
+}
+
+func (this *bindCounter) Add(n int) int {
+	this.count += n
+	return this.count
+}
+
+func (this *bindCounter) Get() int {
+	return this.count
+}
+
+func TestBind(t *testing.T) {
+	counter := &bindCounter{}
+	add := Bind(counter, "Add")
+	asserts.IntEquals(t, "can bind a method to its object", add(5).(int), 5)
+
+	addTen := Bind(counter, "Add", 10)
+	addTen()
+	asserts.IntEquals(t, "can bind with arguments", counter.count, 15)
+
+	defer func() {
+		asserts.True(t, "binding a missing method panics", recover() != nil)
+	}()
+	Bind(counter, "Nope")
+}
 
 func TestPartial(t *testing.T) {
 
@@ -44,9 +66,74 @@ func TestPartial(t *testing.T) {
 
 	asserts.Equals(t, "can partially apply",
 		fmt.Sprint(passAB("1", 2)), "[a b 1 2]")
+
+	saved := make([]T, 2, 10)
+	saved[0], saved[1] = "a", "b"
+	shared := Partial(funk, saved...)
+	first := shared("c")
+	shared("x", "y")
+	asserts.Equals(t, "repeated calls don't share args", fmt.Sprint(first, shared("c")), "[a b c][a b c]")
+
+	var wg sync.WaitGroup
+	results := make([]T, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = shared(i)
+		}(i)
+	}
+	wg.Wait()
+	asserts.Equals(t, "concurrent calls don't share args", fmt.Sprint(results[7], results[42]), "[a b 7][a b 42]")
+
+	subtract := func(args ...T) T { return args[0].(int) - args[1].(int) }
+	asserts.IntEquals(t, "can partially apply with placeholders", Partial(subtract, Placeholder, 5)(20).(int), 15)
+	asserts.Equals(t, "placeholders are filled in order, extra args appended",
+		fmt.Sprint(Partial(funk, Placeholder, "b", Placeholder)("a", "c", "d")), "[a b c d]")
+	asserts.True(t, "unfilled placeholders are passed along",
+		Partial(func(args ...T) T { return args[1] }, "a", Placeholder)() == Placeholder)
 }
 
-// XXX: missing bindAll
+func TestPartialRight(t *testing.T) {
+	funk := func(args ...T) T {
+		return fmt.Sprint(args)
+	}
+	asserts.Equals(t, "fills the rightmost arguments", fmt.Sprint(PartialRight(funk, "c", "d")("a", "b")), "[a b c d]")
+	asserts.Equals(t, "fills placeholders from the last arguments",
+		fmt.Sprint(PartialRight(funk, Placeholder, "!")("hi", "moe")), "[hi moe !]")
+}
+
+func TestCurry(t *testing.T) {
+	add3 := CurryN(func(args ...T) T { return args[0].(int) + args[1].(int) + args[2].(int) }, 3)
+	asserts.IntEquals(t, "one at a time", add3(1).(func(...T) T)(2).(func(...T) T)(3).(int), 6)
+	asserts.IntEquals(t, "some at a time", add3(1, 2).(func(...T) T)(3).(int), 6)
+	asserts.IntEquals(t, "all at once", add3(1, 2, 3).(int), 6)
+	addOne := add3(1).(func(...T) T)
+	asserts.True(t, "curried functions can be reused", addOne(1, 1).(int) == 3 && addOne(2, 2).(int) == 5)
+
+	join := Curry(func(a string, b int, c float64) string { return fmt.Sprint(a, b, c) })
+	asserts.Equals(t, "curries typed functions", join("x").(func(...T) T)(1).(func(...T) T)(1.5).(string), "x1 1.5")
+	asserts.Equals(t, "converts numbers", join("x", 1.0, 2).(string), "x1 2")
+
+	type label string
+	tag := Curry(func(l label, d time.Duration) string { return fmt.Sprint("[", l, "] ", d) })
+	asserts.Equals(t, "converts to named types of the same kind", tag("moe", 2).(string), "[moe] 2ns")
+	defer func() {
+		asserts.True(t, "but not numbers to strings", recover() != nil)
+	}()
+	Curry(func(s string) string { return s })(5)
+}
+
+func TestBindAll(t *testing.T) {
+	counter := &bindCounter{}
+	bound := BindAll(counter, "Add", "Get")
+	bound["Add"](3)
+	asserts.IntEquals(t, "binds the named methods", bound["Get"]().(int), 3)
+
+	everything := BindAll(counter)
+	asserts.IntEquals(t, "binds every method by default", len(everything), 2)
+	asserts.IntEquals(t, "bound to the same object", everything["Add"](1).(int), 4)
+}
 
 func TestMemoize(t *testing.T) {
 	asserts.IntEquals(t, "a memoized version of fibonacci produces identical results",
@@ -61,6 +148,130 @@ func TestMemoize(t *testing.T) {
 	asserts.Equals(t, "blah blah", fastO("toString").(string), "toString")
 }
 
+func TestMemoizeAllArgs(t *testing.T) {
+	calls := 0
+	add := Memoize(func(args ...T) T {
+		calls += 1
+		return args[0].(int) + args[1].(int)
+	})
+	asserts.IntEquals(t, "memoized add", add(1, 2).(int), 3)
+	asserts.IntEquals(t, "keyed on every argument, not just the first", add(1, 5).(int), 6)
+	add(1, 2)
+	asserts.IntEquals(t, "repeat call is cached", calls, 2)
+
+	type point struct{ X int }
+	calls = 0
+	deref := Memoize(func(args ...T) T {
+		calls += 1
+		return args[0].(*point).X
+	})
+	a, b := &point{1}, &point{1}
+	deref(a)
+	deref(b)
+	asserts.IntEquals(t, "pointers are keyed by address", calls, 2)
+	a.X = 2
+	asserts.IntEquals(t, "so changes behind them aren't seen", deref(a).(int), 1)
+}
+
+func TestMemoizer(t *testing.T) {
+	calls := 0
+	m := NewMemoizer(func(args ...T) T {
+		calls += 1
+		return args[0]
+	}, MemoizeOptions{MaxSize: 2})
+	m.Call("a")
+	m.Call("b")
+	m.Call("a")
+	m.Call("c")
+	m.Call("a")
+	asserts.IntEquals(t, "LRU keeps the recently used a", calls, 3)
+	m.Call("b")
+	asserts.IntEquals(t, "LRU evicted b", calls, 4)
+	stats := m.Stats()
+	asserts.Equals(t, "stats", fmt.Sprint(stats), fmt.Sprint(MemoizeStats{Hits: 2, Misses: 4, Evictions: 2, Size: 2}))
+
+	m.Forget("b")
+	m.Call("b")
+	asserts.IntEquals(t, "forgotten result is recomputed", calls, 5)
+	m.Clear()
+	asserts.IntEquals(t, "cleared", m.Stats().Size, 0)
+
+	calls = 0
+	lfu := NewMemoizer(func(args ...T) T {
+		calls += 1
+		return args[0]
+	}, MemoizeOptions{MaxSize: 2, Eviction: EvictLFU})
+	lfu.Call("a")
+	lfu.Call("a")
+	lfu.Call("b")
+	lfu.Call("c")
+	lfu.Call("a")
+	asserts.IntEquals(t, "LFU keeps the frequently used a", calls, 3)
+	lfu.Call("b")
+	lfu.Call("b")
+	asserts.IntEquals(t, "and drops the less used c for b", calls, 4)
+	lfu.Forget("a")
+	lfu.Call("d")
+	lfu.Call("d")
+	lfu.Call("e")
+	lfu.Call("d")
+	asserts.IntEquals(t, "ties keep the more recently used d", calls, 6)
+	lfu.Call("b")
+	asserts.IntEquals(t, "and drop b", calls, 7)
+
+	calls = 0
+	clock := NewManualClock()
+	ttl := NewMemoizer(func(args ...T) T {
+		calls += 1
+		return args[0]
+	}, MemoizeOptions{TTL: 10 * time.Millisecond, Clock: clock})
+	ttl.Call("a")
+	ttl.Call("a")
+	clock.Advance(20 * time.Millisecond)
+	ttl.Call("a")
+	asserts.IntEquals(t, "expired result is recomputed", calls, 2)
+	asserts.IntEquals(t, "expirations counted", ttl.Stats().Expirations, 1)
+}
+
+func TestMemoizerConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	release := make(chan struct{})
+	m := NewMemoizer(func(args ...T) T {
+		mu.Lock()
+		calls += 1
+		mu.Unlock()
+		<-release
+		return args[0]
+	}, MemoizeOptions{})
+	var wg sync.WaitGroup
+	results := make([]T, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = m.Call("slow")
+		}(i)
+	}
+	for m.Stats().Misses+m.Stats().Shared < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	asserts.IntEquals(t, "identical concurrent calls run the function once", calls, 1)
+	asserts.IntEquals(t, "the rest shared the result", m.Stats().Shared, 9)
+	asserts.Equals(t, "every caller got the result", fmt.Sprint(Uniq(results, false)), "[slow]")
+
+	panicky := NewMemoizer(func(args ...T) T { panic("boom") }, MemoizeOptions{})
+	func() {
+		defer func() {
+			asserts.Equals(t, "panic passed on", fmt.Sprint(recover()), "boom")
+		}()
+		panicky.Call(1)
+	}()
+	asserts.IntEquals(t, "panics aren't cached", panicky.Stats().Size, 0)
+}
+
 
 func TestOnce(t *testing.T) {
 	num := 0
@@ -73,7 +284,122 @@ func TestOnce(t *testing.T) {
 	asserts.IntEquals(t, "can increment once", num, 1)
 }
 
-// TODO: missing recursive once
+func TestOnceConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	arrived := make(chan struct{})
+	setup := Once(func(...T) T {
+		// wait for the other callers to arrive, so they call while this is running
+		for i := 0; i < 19; i++ {
+			<-arrived
+		}
+		mu.Lock()
+		calls += 1
+		mu.Unlock()
+		return "ready"
+	})
+	var wg sync.WaitGroup
+	results := make([]T, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i > 0 {
+				arrived <- struct{}{}
+			}
+			results[i] = setup()
+		}(i)
+	}
+	wg.Wait()
+	asserts.IntEquals(t, "called once from many goroutines", calls, 1)
+	asserts.True(t, "callers wait for the result while it's running",
+		Every(results, func(result, i, list T) bool { return result == "ready" }))
+	asserts.Equals(t, "the result once it has run", setup().(string), "ready")
+
+	panicky := Once(func(...T) T { panic("boom") })
+	for i := 0; i < 2; i++ {
+		func() {
+			defer func() {
+				asserts.Equals(t, "every call re-panics", fmt.Sprint(recover()), "boom")
+			}()
+			panicky()
+		}()
+	}
+}
+
+func TestOnceWithError(t *testing.T) {
+	attempts := 0
+	connect := OnceWithError(func(args ...T) (T, error) {
+		attempts += 1
+		if attempts == 2 {
+			panic("flaky")
+		}
+		if attempts < 3 {
+			return nil, errors.New("refused")
+		}
+		return fmt.Sprint("connected to ", args[0]), nil
+	})
+	_, err := connect("db")
+	asserts.True(t, "errors are returned", err != nil)
+	func() {
+		defer func() {
+			asserts.Equals(t, "panics are passed on", fmt.Sprint(recover()), "flaky")
+		}()
+		connect("db")
+	}()
+	conn, err := connect("db")
+	asserts.Nil(t, "retried after the error and panic", err)
+	asserts.Equals(t, "succeeds", conn.(string), "connected to db")
+	conn, _ = connect("other")
+	asserts.Equals(t, "then remembers the success", conn.(string), "connected to db")
+	asserts.IntEquals(t, "attempts", attempts, 3)
+
+	var mu sync.Mutex
+	dials := 0
+	arrived := make(chan struct{})
+	dial := OnceWithError(func(...T) (T, error) {
+		for i := 0; i < 9; i++ {
+			<-arrived
+		}
+		mu.Lock()
+		dials += 1
+		mu.Unlock()
+		return "conn", nil
+	})
+	var wg sync.WaitGroup
+	results := make([]T, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i > 0 {
+				arrived <- struct{}{}
+			}
+			results[i], _ = dial()
+		}(i)
+	}
+	wg.Wait()
+	asserts.IntEquals(t, "called once from many goroutines", dials, 1)
+	asserts.True(t, "callers wait for the result while it's running",
+		Every(results, func(result, i, list T) bool { return result == "conn" }))
+
+	var recursive func(...T) (T, error)
+	recursive = OnceWithError(func(...T) (T, error) {
+		_, err := recursive()
+		return err, nil
+	})
+	result, _ := recursive()
+	asserts.True(t, "recursive calls get an error rather than deadlocking", result != nil)
+}
+
+func TestRecursiveOnce(t *testing.T) {
+	var f func(...T) T
+	f = Once(func(...T) T {
+		return fmt.Sprint("foo", f())
+	})
+	asserts.Equals(t, "recursive calls get nil", f().(string), "foo<nil>")
+	asserts.Equals(t, "then the result", f().(string), "foo<nil>")
+}
 
 func TestWrap(t *testing.T) {
 	// from http://play.golang.org/p/Ic5G5QEO93
@@ -131,6 +457,85 @@ func TestCompose(t *testing.T) {
 
 	composed4 := Compose(greet, exclaim, pause)
 	asserts.Equals(t, "in this case, the functions are not commutative", composed4("moe").(string), "hi: moe, !")
+
+	count := func(args ...T) T { return len(args) }
+	asserts.IntEquals(t, "a []T result is spread into the arguments",
+		Compose(count, func(...T) T { return []T{1, 2, 3} })().(int), 3)
+	asserts.IntEquals(t, "a nil result passes no arguments", Compose(count, func(...T) T { return nil })().(int), 0)
+	asserts.IntEquals(t, "but the composed function's arguments are passed as they are",
+		Compose(count)([]T{1, 2, 3}).(int), 1)
+	asserts.Equals(t, "and the last result is returned whole",
+		fmt.Sprint(Compose(func(...T) T { return []T{1, 2} })()), "[1 2]")
+}
+
+func TestFlow(t *testing.T) {
+	divmod := func(a, b int) (int, int) { return a / b, a % b }
+	sum := func(nums ...int) int {
+		total := 0
+		for _, n := range nums {
+			total += n
+		}
+		return total
+	}
+	describe := func(n int) string { return fmt.Sprintf("<%d>", n) }
+	flow, err := Flow(divmod, sum, describe)
+	asserts.Nil(t, "functions of any arity fit together", err)
+	result, err := flow(17, 5)
+	asserts.Equals(t, "passes every result on", result.(string), "<5>")
+	asserts.Nil(t, "no error", err)
+
+	right, _ := FlowRight(describe, sum, divmod)
+	result, _ = right(17, 5)
+	asserts.Equals(t, "FlowRight runs right to left", result.(string), "<5>")
+
+	result, _ = Pipe(3, func(n int) int { return n * 2 }, func(n float64) float64 { return n + 0.5 })
+	asserts.Equals(t, "Pipe passes a value through, converting numbers", fmt.Sprint(result), "6.5")
+	result, _ = Pipe("as is")
+	asserts.Equals(t, "no functions is the identity", result.(string), "as is")
+	multi, _ := Flow(divmod)
+	result, _ = multi(7, 2)
+	asserts.Equals(t, "several results come back as a list", fmt.Sprint(result), "[3 1]")
+
+	_, err = Flow(describe, "not a function")
+	asserts.Equals(t, "rejects things that aren't functions", fmt.Sprint(err), "Flow: funcs[1] is string, not a function")
+	_, err = Flow(divmod, describe)
+	asserts.Equals(t, "checks the number of values passed", fmt.Sprint(err),
+		"Flow: funcs[0] (func(int, int) (int, int)) passes 2 values, funcs[1] (func(int) string) takes 1")
+	_, err = Flow(describe, sum)
+	asserts.Equals(t, "checks the types passed", fmt.Sprint(err),
+		"Flow: funcs[0] (func(int) string) passes a string, funcs[1] (func(...int) int) takes a int")
+
+	errNegative := errors.New("negative")
+	check := func(n int) (int, error) {
+		if n < 0 {
+			return 0, errNegative
+		}
+		return n, nil
+	}
+	checked, err := Flow(check, describe)
+	asserts.Nil(t, "an error result isn't passed on", err)
+	result, _ = checked(1)
+	asserts.Equals(t, "passes the value on", result.(string), "<1>")
+	_, err = checked(-1)
+	asserts.True(t, "an error stops the flow", errors.Is(err, errNegative))
+	_, err = checked("one")
+	asserts.Equals(t, "bad arguments are an error", fmt.Sprint(err), "Flow: funcs[0]: can't pass one as int")
+
+	async := func(n int) <-chan int {
+		c := make(chan int)
+		go func() { c <- n * 10 }()
+		return c
+	}
+	asyncFlow, err := Flow(async, describe)
+	asserts.Nil(t, "async stages pass on their channel's type", err)
+	result, _ = asyncFlow(4)
+	asserts.Equals(t, "waits on the channel", result.(string), "<40>")
+
+	defer func() {
+		asserts.Equals(t, "Compose panics when built with a bad function", fmt.Sprint(recover()),
+			"Flow: funcs[0] is <nil>, not a function")
+	}()
+	Compose(nil, describe)
 }
 
 func TestAfter(t *testing.T) {
@@ -157,356 +562,879 @@ func TestAfter(t *testing.T) {
 		testAfter(0, 1), 1)
 }
 
+func TestAfterConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	after := After(5, func(...T) T {
+		mu.Lock()
+		calls += 1
+		mu.Unlock()
+		return nil
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			after()
+		}()
+	}
+	wg.Wait()
+	asserts.IntEquals(t, "fires from the 5th call on, whichever goroutines make them", calls, 16)
+}
+
+func TestBefore(t *testing.T) {
+	testBefore := func(beforeAmount, timesCalled int) int {
+		beforeCalled := 0
+		before := Before(beforeAmount, func(...T) T {
+			beforeCalled += 1
+			return beforeCalled
+		})
+		for timesCalled > 0 {
+			timesCalled -= 1
+			before()
+		}
+		return beforeCalled
+	}
+	asserts.IntEquals(t, "before(N) should not fire after being called N times",
+		testBefore(5, 5), 4)
+	asserts.IntEquals(t, "before(N) should fire before being called N times",
+		testBefore(5, 4), 4)
+	asserts.IntEquals(t, "before(0) should not fire immediately",
+		testBefore(0, 0), 0)
+	asserts.IntEquals(t, "before(0) should not fire when first invoked",
+		testBefore(0, 1), 0)
+
+	increment := Before(3, func(...T) T { return 42 })
+	increment()
+	increment()
+	asserts.IntEquals(t, "stores a memo to the last value", increment().(int), 42)
+}
+
+func TestLimit(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	limited := Limit(func(...T) T {
+		mu.Lock()
+		defer mu.Unlock()
+		calls += 1
+		return calls
+	}, 3)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limited()
+		}()
+	}
+	wg.Wait()
+	asserts.IntEquals(t, "called at most n times from many goroutines", calls, 3)
+	asserts.IntEquals(t, "then returns the last result", limited().(int), 3)
+
+	var recursive func(...T) T
+	depth := 0
+	recursive = Limit(func(...T) T {
+		depth += 1
+		return recursive()
+	}, 2)
+	recursive()
+	asserts.IntEquals(t, "may call itself", depth, 2)
+}
+
+func TestRateLimiter(t *testing.T) {
+	clock := NewManualClock()
+	calls := 0
+	limiter := WithClock(clock).NewRateLimiter(func(args ...T) T {
+		calls += 1
+		return args[0]
+	}, 10, 2)
+	_, ok1 := limiter.TryCall(1)
+	_, ok2 := limiter.TryCall(2)
+	_, ok3 := limiter.TryCall(3)
+	asserts.True(t, "a burst of 2 is allowed", ok1 && ok2)
+	asserts.False(t, "the 3rd is refused", ok3)
+	asserts.IntEquals(t, "refused calls aren't made", calls, 2)
+	clock.Advance(100 * time.Millisecond)
+	result, ok := limiter.TryCall(4)
+	asserts.True(t, "a token is added every 1/rate seconds", ok && result.(int) == 4)
+
+	done := make(chan T)
+	go func() {
+		done <- limiter.Call(5)
+	}()
+	for clock.Pending() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	asserts.IntEquals(t, "Call blocks until there's a token", calls, 3)
+	clock.Advance(100 * time.Millisecond)
+	asserts.IntEquals(t, "then calls", (<-done).(int), 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := limiter.CallContext(ctx, 6)
+	asserts.True(t, "a done context stops the wait", err == context.Canceled)
+
+	unlimited := RateLimit(func(...T) T { return "ok" }, 0, 1)
+	for i := 0; i < 100; i++ {
+		unlimited()
+	}
+	asserts.Equals(t, "a rate of 0 is no limit", unlimited().(string), "ok")
+}
+
+func TestBackoff(t *testing.T) {
+	asserts.Equals(t, "fixed backoff", fmt.Sprint(FixedBackoff(time.Second)(1), FixedBackoff(time.Second)(5)), "1s 1s")
+	exp := ExponentialBackoff(100*time.Millisecond, time.Second)
+	asserts.Equals(t, "exponential backoff doubles up to the max",
+		fmt.Sprint(Map(Range(1, 7), func(n, i, l T) T { return exp(n.(int)) })), "[100ms 200ms 400ms 800ms 1s 1s]")
+	asserts.True(t, "exponential backoff doesn't overflow", ExponentialBackoff(time.Second, 0)(100) > 0)
+	for i := 0; i < 20; i++ {
+		wait := Jittered(FixedBackoff(time.Second))(1)
+		asserts.True(t, "jittered backoff is between 0 and the wait", wait >= 0 && wait <= time.Second)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	attempts := 0
+	flaky := func(args ...T) (T, error) {
+		attempts += 1
+		if attempts < 3 {
+			return nil, errFlaky
+		}
+		return args[0], nil
+	}
+	result, err := Retry(flaky, RetryPolicy{})(42)
+	asserts.Nil(t, "succeeds on the third attempt", err)
+	asserts.IntEquals(t, "result of the successful attempt", result.(int), 42)
+	asserts.IntEquals(t, "attempts", attempts, 3)
+
+	attempts = 0
+	_, err = Retry(flaky, RetryPolicy{MaxAttempts: 2})(42)
+	var retryErr *RetryError
+	asserts.True(t, "gives up with a RetryError", errors.As(err, &retryErr) && retryErr.Attempts == 2)
+	asserts.True(t, "which wraps the last error", errors.Is(err, errFlaky))
+
+	errFatal := errors.New("fatal")
+	attempts = 0
+	_, err = Retry(func(...T) (T, error) {
+		attempts += 1
+		return nil, errFatal
+	}, RetryPolicy{Retryable: func(err error) bool { return err != errFatal }})()
+	asserts.True(t, "errors that aren't retryable are returned as is", err == errFatal)
+	asserts.IntEquals(t, "after one attempt", attempts, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Retry(flaky, RetryPolicy{Context: ctx})()
+	asserts.True(t, "a done context stops retrying", err == context.Canceled)
+
+	clock := NewManualClock()
+	ctx, cancel = context.WithCancel(context.Background())
+	attempts = 0
+	done := make(chan error)
+	go func() {
+		_, err := Retry(flaky, RetryPolicy{Context: ctx, Backoff: FixedBackoff(time.Second), Clock: clock})()
+		done <- err
+	}()
+	for clock.Pending() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	err = <-done
+	asserts.True(t, "cancelled while waiting gives a RetryError", errors.As(err, &retryErr) && retryErr.Attempts == 1)
+	asserts.True(t, "which wraps the context's error", errors.Is(err, context.Canceled))
+	asserts.True(t, "and the last error", errors.Is(err, errFlaky))
+}
+
+func TestRetryMaxElapsedOnly(t *testing.T) {
+	clock := NewManualClock()
+	failures := 0
+	failing := func(...T) (T, error) {
+		failures += 1
+		return nil, errors.New("down")
+	}
+	retry := Retry(failing, RetryPolicy{MaxElapsed: 25 * time.Millisecond, Clock: clock})
+	done := make(chan error)
+	go func() {
+		_, err := retry()
+		done <- err
+	}()
+	for {
+		select {
+		case err := <-done:
+			asserts.IntEquals(t, "waits 10ms between attempts without a Backoff, rather than spinning", failures, 3)
+			asserts.True(t, "gave up", err != nil && err.(*RetryError).Attempts == 3)
+			return
+		default:
+		}
+		if clock.Pending() > 0 {
+			clock.Advance(retryMinWait)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRetryMaxElapsedWithBackoff(t *testing.T) {
+	clock := NewManualClock()
+	failures := 0
+	failing := func(...T) (T, error) {
+		failures += 1
+		return nil, errors.New("down")
+	}
+	retry := Retry(failing, RetryPolicy{MaxElapsed: 3 * time.Millisecond, Backoff: FixedBackoff(time.Millisecond), Clock: clock})
+	done := make(chan error)
+	go func() {
+		_, err := retry()
+		done <- err
+	}()
+	for {
+		select {
+		case <-done:
+			asserts.IntEquals(t, "the Backoff given isn't raised to 10ms", failures, 4)
+			return
+		default:
+		}
+		if clock.Pending() > 0 {
+			clock.Advance(time.Millisecond)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	clock := NewManualClock()
+	failures := 0
+	failing := func(...T) (T, error) {
+		failures += 1
+		return nil, errors.New("down")
+	}
+	retry := Retry(failing, RetryPolicy{
+		MaxElapsed: 350 * time.Millisecond,
+		Backoff:    ExponentialBackoff(100*time.Millisecond, 0),
+		Clock:      clock,
+	})
+	done := make(chan error)
+	go func() {
+		_, err := retry()
+		done <- err
+	}()
+	waits := 0
+	for {
+		select {
+		case err := <-done:
+			asserts.IntEquals(t, "waited 100ms then 200ms, the next 400ms would pass MaxElapsed", waits, 2)
+			asserts.IntEquals(t, "attempts within MaxElapsed", failures, 3)
+			asserts.True(t, "gave up", err != nil && err.(*RetryError).Attempts == 3)
+			return
+		default:
+		}
+		if clock.Pending() > 0 {
+			waits += 1
+			clock.Advance(ExponentialBackoff(100*time.Millisecond, 0)(waits))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
 
 func TestNow(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 42))
+	timing := WithClock(clock)
+	asserts.True(t, "Reads the clock given", timing.Now() == 42 && timing.NowNano() == 42)
+	clock.Advance(time.Millisecond)
+	asserts.True(t, "Follows the clock", timing.Now() == clock.Now().UnixNano())
 	diff := Now() - time.Now().UnixNano()
-	asserts.True(t, "Produces the correct time in milliseconds", diff <= 0 && diff > -50 );//within 50ns
+	asserts.True(t, "Defaults to the system clock", diff <= 0 && diff > -int64(time.Second))
+}
+
+func TestManualClock(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	fired := []string{}
+	clock.AfterFunc(20*time.Millisecond, func() { fired = append(fired, "b") })
+	clock.AfterFunc(10*time.Millisecond, func() {
+		fired = append(fired, "a")
+		clock.AfterFunc(5*time.Millisecond, func() { fired = append(fired, "a2") })
+	})
+	stopped := clock.AfterFunc(15*time.Millisecond, func() { fired = append(fired, "stopped") })
+	asserts.True(t, "stop a pending timer", stopped.Stop())
+	asserts.False(t, "stop a stopped timer", stopped.Stop())
+	timer := clock.NewTimer(30 * time.Millisecond)
+	asserts.IntEquals(t, "pending timers", clock.Pending(), 3)
+
+	clock.Advance(25 * time.Millisecond)
+	asserts.Equals(t, "timers fire in time order, including those set while advancing",
+		fmt.Sprint(fired), "[a a2 b]")
+	select {
+	case <-timer.C():
+		t.Error("timer fired early")
+	default:
+	}
+	asserts.True(t, "reset a pending timer", timer.Reset(10*time.Millisecond))
+	clock.Advance(9 * time.Millisecond)
+	asserts.IntEquals(t, "reset timer not due yet", clock.Pending(), 1)
+	clock.Advance(time.Millisecond)
+	asserts.True(t, "timer sends the time it fired", (<-timer.C()).Equal(time.Unix(0, int64(35*time.Millisecond))))
+	asserts.IntEquals(t, "no timers left", clock.Pending(), 0)
 }
 
 func TestDelay(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
 	delayed := false
-	Delay(func() T { delayed = true; return delayed }, 100)
-	Delay(func() T {
-		asserts.False( t, "didn't delay the function quite yet", delayed)
-		return delayed },
-		50)
-	Delay(func() T {
-		asserts.True( t, "delayed the function", delayed)
+	checks := 0
+	timing.Delay(func(...T) T { delayed = true; return delayed }, 100)
+	timing.Delay(func(...T) T {
+		asserts.False(t, "didn't delay the function quite yet", delayed)
+		checks += 1
+		return delayed
+	}, 50)
+	timing.Delay(func(...T) T {
+		asserts.True(t, "delayed the function", delayed)
+		checks += 1
 		return delayed
-	},
-		150)
+	}, 150)
+	clock.Advance(300 * time.Millisecond)
+	asserts.IntEquals(t, "every delayed function ran", checks, 2)
 
-	// wait for Delay calls above to run their course
+	add := func(args ...T) T { return args[0].(int) + args[1].(int) }
+	handle := timing.Delay(add, 100, 1, 2)
 	select {
-		case <-time.After(300 * time.Millisecond):
-		break
+	case <-handle.Done():
+		t.Error("done before the wait")
+	default:
 	}
+	clock.Advance(100 * time.Millisecond)
+	<-handle.Done()
+	asserts.IntEquals(t, "arguments are passed to the function", handle.Result().(int), 3)
+	asserts.False(t, "too late to cancel", handle.Cancel())
+
+	ran := false
+	cancelled := timing.Delay(func(...T) T { ran = true; return ran }, 100)
+	asserts.True(t, "cancel a pending call", cancelled.Cancel())
+	clock.Advance(time.Second)
+	asserts.False(t, "cancelled call isn't made", ran)
+	asserts.True(t, "cancelled", cancelled.Cancelled())
+	asserts.Nil(t, "cancelled call has no result", cancelled.Result())
+	asserts.Equals(t, "wait for the result on the system clock",
+		Delay(func(args ...T) T { return args[0] }, 1, "logged later").Result().(string), "logged later")
 }
 
+// Adapt a debounced func() T for Delay
+func delayable(fn func() T) func(...T) T {
+	return func(...T) T { return fn() }
+}
 
 func TestDefer(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
 	deferred := false
-	func(){
-		defer func(boole bool){ deferred = boole }(true)
-		Delay(func() T{ asserts.Ok(t, "deferred the function", deferred) ; return deferred }, 50)
+	func() {
+		defer func(boole bool) { deferred = boole }(true)
+		timing.Delay(func(...T) T { asserts.Ok(t, "deferred the function", deferred); return deferred }, 50)
 	}()
-	// wait for Delay calls above to run their course
-	select {
-		case <-time.After(100 * time.Millisecond):
-		break
-	}
+	clock.Advance(100 * time.Millisecond)
+
+	deferredArgs := timing.Defer(func(args ...T) T { return fmt.Sprint(args) }, "a", "b")
+	clock.Advance(0)
+	asserts.Equals(t, "deferred with arguments", deferredArgs.Result().(string), "[a b]")
 }
 
-// XXX: debounce, tests in progress
 func TestDebounce(t *testing.T) {
-	var counter int = 0
-	incr := func() T { counter += 1
-		return counter }
-	debouncedIncr := Debounce(incr, 32)
+	clock := NewManualClock()
+	timing := WithClock(clock)
+	counter := 0
+	incr := func() T { counter += 1; return counter }
+	debouncedIncr := timing.Debounce(incr, 32)
 	debouncedIncr()
 	debouncedIncr()
-	Delay(debouncedIncr, 32)
-	//Delay(func(){ asserts.IntEquals(t, "incr was debounced", counter, 0) },
-		//96)
-	select {
-		case <-time.After(5 * time.Millisecond):
-			asserts.IntEquals(t, "incr was debounced", counter, 0)
-		break
-	}
-	select {
-		case <-time.After(90 * time.Millisecond):
-			asserts.IntEquals(t, "incr was debounced", counter, 1)
-		break
-	}
+	timing.Delay(delayable(debouncedIncr), 32)
+	clock.Advance(5 * time.Millisecond)
+	asserts.IntEquals(t, "incr was debounced", counter, 0)
+	clock.Advance(27 * time.Millisecond)
+	asserts.IntEquals(t, "incr was debounced, before the delayed call due at the same time", counter, 1)
+	clock.Advance(63 * time.Millisecond)
+	asserts.IntEquals(t, "the delayed call was debounced too", counter, 2)
 }
 
 func TestDebounceASAP(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
 	counter := 0
 	incr := func() T { counter += 1; return counter }
-	debouncedIncr := Debounce(incr, 64, true)
+	debouncedIncr := timing.Debounce(incr, 64, true)
 	a := debouncedIncr().(int)
 	b := debouncedIncr().(int)
-	asserts.IntEquals(t, "debounced immediate return a",a, 1)
-	asserts.IntEquals(t, "debounced immediate return b",b, 1)
-	asserts.IntEquals(t, "incr was called immediately",1,counter)
-	Delay(debouncedIncr, 16)
-	Delay(debouncedIncr, 32)
-	Delay(debouncedIncr, 48)
-	 globalWg.Add(1)
-	/*w*/Delay(func () T {
-			asserts.IntEquals(t, "incr was debounced", counter, 1)
-		globalWg.Done()
-		return counter
-	},128)
+	asserts.IntEquals(t, "debounced immediate return a", a, 1)
+	asserts.IntEquals(t, "debounced immediate return b", b, 1)
+	asserts.IntEquals(t, "incr was called immediately", 1, counter)
+	timing.Delay(delayable(debouncedIncr), 16)
+	timing.Delay(delayable(debouncedIncr), 32)
+	timing.Delay(delayable(debouncedIncr), 48)
+	clock.Advance(128 * time.Millisecond)
+	asserts.IntEquals(t, "incr was debounced", counter, 1)
 }
 
 func TestDebounceASAPRecursively(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
 	counter := 0
-	var debouncedIncr func()T
-	debouncedIncr = Debounce(func() T{
-			counter += 1
-			if counter < 10 {
-				debouncedIncr()
-			}
-			return counter
-		}, 32, true)
+	var debouncedIncr func() T
+	debouncedIncr = timing.Debounce(func() T {
+		counter += 1
+		if counter < 10 {
+			debouncedIncr()
+		}
+		return counter
+	}, 32, true)
 	debouncedIncr()
 	asserts.IntEquals(t, "incr was called immediately", counter, 1)
-	//Delay(func() T{ asserts.IntEquals( t, "Incr was debounced, recursively", counter, 1); return nil },
-		//96)
-	select {
-		case <-time.After(96 * time.Millisecond):
-			asserts.IntEquals(t, "incr was debounced, recursively", counter, 1)
-		break
-	}
+	clock.Advance(96 * time.Millisecond)
+	asserts.IntEquals(t, "incr was debounced, recursively", counter, 1)
 }
 
-/* not relevant to Go, can't redefine Now()
-func TestDebounceAfterSystemTimeIsMuckedWith(t *testing.T) {
-    counter := 0
-    origNowFunc := Now
-    debouncedIncr := Debounce(func()T{ counter += 1; return counter }, 100, true)
-    debouncedIncr();
-    asserts.IntEquals(t, "Incr called immediately",counter, 1)
-    Now = func() int64 {
-      return 201301111
-    }
-
-	select {
-		case <-time.After(200 * time.Millisecond):
-			debouncedIncr()
-			asserts.IntEquals(t, "incr was debounced, successively", counter, 2)
-			Now = origNowFunc
-		break
-	}
-  }
-*/
-
-func TestThrottle(t *testing.T){
+func TestThrottle(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
 	counter := 0
-	incr := func(...T) T { counter += 1; return counter}
-	throttledIncr := Throttle(incr, 32)
+	incr := func(...T) T { counter += 1; return counter }
+	throttledIncr := timing.Throttle(incr, 32)
 	throttledIncr()
 	throttledIncr()
-   asserts.IntEquals(t, "incr was called immediately", counter, 1)
-	 globalWg.Add(1)
-	/*w*/Delay(func () T {
-		asserts.IntEquals(t, "incr was throttled", counter, 2)
-		globalWg.Done()
-		return counter
-	},64)
+	asserts.IntEquals(t, "incr was called immediately", counter, 1)
+	clock.Advance(64 * time.Millisecond)
+	asserts.IntEquals(t, "incr was throttled", counter, 2)
 }
 
-func TestThrottleWithArgs (t *testing.T){
-	var value int = 0
+func TestThrottleWithArgs(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
+	value := 0
 	update := func(val ...T) T {
 		if len(val) > 0 {
 			value = (val[0]).(int)
 		}
 		return value
 	}
-	throttledUpdate := Throttle(update, 32)
+	throttledUpdate := timing.Throttle(update, 32)
 	throttledUpdate(1)
 	throttledUpdate(2)
-	Delay(func() T {
+	timing.Delay(func(...T) T {
 		throttledUpdate(3)
 		return value
 	}, 85)
 	asserts.IntEquals(t, "updated to first value", value, 1)
-	 globalWg.Add(1)
-	/*w*/Delay(func() T {
-		asserts.IntEquals(t, "updated to latest value", value, 3)
-		globalWg.Done()
-		return nil }, 100)
-}
-
-func TestThrottleOnce(t *testing.T){
-    counter := 0
-    incr := func(...T) T{ counter += 1; return counter }
-    throttledIncr := Throttle(incr, 2)
-    result := throttledIncr().(int)
-	 globalWg.Add(1)
-    /*w*/Delay(func()T {
-      asserts.IntEquals( t, "throttled functions return their value", result, 1)
-      asserts.IntEquals( t, "Incr was called once ", counter , 1)
-		globalWg.Done()
-		return nil
-    }, 6)
-}
-
-func TestThrottleTwice(t *testing.T){
-    counter := 0
-    incr := func(...T) T{ counter += 1; return counter }
-    throttledIncr := Throttle(incr, 2)
-    throttledIncr()
-    throttledIncr()
-	 globalWg.Add(1)
-    /*w*/Delay(func()T {
-      asserts.IntEquals( t, "Incr was called twice ", counter , 2)
-		globalWg.Done()
-		return nil
-    }, 6)
+	clock.Advance(100 * time.Millisecond)
+	asserts.IntEquals(t, "updated to latest value", value, 3)
+}
+
+func TestThrottleOnce(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
+	counter := 0
+	incr := func(...T) T { counter += 1; return counter }
+	throttledIncr := timing.Throttle(incr, 2)
+	result := throttledIncr().(int)
+	clock.Advance(6 * time.Millisecond)
+	asserts.IntEquals(t, "throttled functions return their value", result, 1)
+	asserts.IntEquals(t, "Incr was called once ", counter, 1)
 }
 
-func TestMoreThrottling(t *testing.T){
+func TestThrottleTwice(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
 	counter := 0
-	incr := func(...T) T{ counter += 1; return counter }
-	throttledIncr := Throttle(incr, 2)
+	incr := func(...T) T { counter += 1; return counter }
+	throttledIncr := timing.Throttle(incr, 2)
 	throttledIncr()
 	throttledIncr()
-   asserts.True(t, "checking counter amidst throttling", counter == 1)
-	 globalWg.Add(1)
-   /*w*/Delay(func()T {
-      asserts.Ok(t,"checking counter amidst throttling, 2",counter == 2)
-      throttledIncr()
-      asserts.Ok(t,"checking counter amidst throttling, 3",counter == 3)
-		globalWg.Done()
-		return nil
-   }, 85)
+	clock.Advance(6 * time.Millisecond)
+	asserts.IntEquals(t, "Incr was called twice ", counter, 2)
 }
 
+func TestMoreThrottling(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
+	counter := 0
+	incr := func(...T) T { counter += 1; return counter }
+	throttledIncr := timing.Throttle(incr, 2)
+	throttledIncr()
+	throttledIncr()
+	asserts.True(t, "checking counter amidst throttling", counter == 1)
+	clock.Advance(85 * time.Millisecond)
+	asserts.Ok(t, "checking counter amidst throttling, 2", counter == 2)
+	throttledIncr()
+	asserts.Ok(t, "checking counter amidst throttling, 3", counter == 3)
+}
 
-func TestThrottleRepeatedlyWithResults( t *testing.T ) {
+func TestThrottleRepeatedlyWithResults(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
 	counter := 0
-	incr := func(...T) T{ counter += 1; return counter }
-	throttledIncr := Throttle(incr, 100)
-   results := []int{}
-	saveResult := func() T { results = append(results,throttledIncr().(int)); return results }
+	incr := func(...T) T { counter += 1; return counter }
+	throttledIncr := timing.Throttle(incr, 100)
+	results := []int{}
+	saveResult := func(...T) T { results = append(results, throttledIncr().(int)); return results }
 	saveResult()
 	saveResult()
-   Delay(saveResult, 50)
-   Delay(saveResult, 150)
-   Delay(saveResult, 160)
-   Delay(saveResult, 230)
-	var localWg sync.WaitGroup
-	localWg.Add(1)
-   /*w*/Delay(func() T {
-      asserts.IntEquals( t, "incr was called once", results[0], 1)
-      asserts.IntEquals( t, "incr was throttled", results[1], 1)
-      asserts.IntEquals( t, "incr was throttled", results[2], 1)
-      asserts.IntEquals( t, "incr was called twice", results[3], 2)
-      asserts.IntEquals( t, "incr was throttled", results[4], 2)
-      asserts.IntEquals( t, "incr was called trailing", results[5], 3)
-		localWg.Done()
-		return nil
-    }, 300)
-	localWg.Wait()
+	timing.Delay(saveResult, 50)
+	timing.Delay(saveResult, 150)
+	timing.Delay(saveResult, 160)
+	timing.Delay(saveResult, 230)
+	clock.Advance(300 * time.Millisecond)
+	asserts.IntEquals(t, "incr was called once", results[0], 1)
+	asserts.IntEquals(t, "incr was throttled", results[1], 1)
+	asserts.IntEquals(t, "incr was throttled", results[2], 1)
+	asserts.IntEquals(t, "incr was called twice", results[3], 2)
+	asserts.IntEquals(t, "incr was throttled", results[4], 2)
+	asserts.IntEquals(t, "incr was called trailing", results[5], 3)
 }
 
-func TestThrottleTriggersTrailingCallWhenInvokedRepeatedly( t *testing.T) {
-    counter := 0
-    counterRet := 0
-    var limit int64 = 4800
-    incr := func(...T) T{ counter += 1; return counter }
-    throttledIncr := Throttle(incr, 32)
+func TestThrottleTriggersTrailingCallWhenInvokedRepeatedly(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
+	counter := 0
+	counterRet := 0
+	limit := int64(48 * time.Millisecond)
+	incr := func(...T) T { counter += 1; return counter }
+	throttledIncr := timing.Throttle(incr, 32)
 
-    stamp := Now()
-    for (Now() - stamp) < limit {
-      throttledIncr()
+	stamp := timing.Now()
+	for (timing.Now() - stamp) < limit {
+		throttledIncr()
 		counterRet += 1
-    }
-    lastCount := counter
-    asserts.Ok( t, "Trailing test", counterRet > 1)
-	 globalWg.Add(1)
-    Delay(func() T {
-		asserts.Ok( t, "Counter > lastCount", counter > lastCount)
-		globalWg.Done()
-		return nil
-    }, 96)
+		clock.Advance(time.Millisecond)
+	}
+	lastCount := counter
+	asserts.Ok(t, "Trailing test", counterRet > 1)
+	clock.Advance(96 * time.Millisecond)
+	asserts.Ok(t, "Counter > lastCount", counter > lastCount)
 }
 
-func TestThrottleDoesNotTriggerLeadingCallWhenLeadingIsFalse (t *testing.T){
+func TestThrottleDoesNotTriggerLeadingCallWhenLeadingIsFalse(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
 	counter := 0
-	incr := func(...T) T{ counter += 1; return counter }
-	throttledIncr := Throttle(incr, 60, map[string]bool{"leading":false,"name":true})
+	incr := func(...T) T { counter += 1; return counter }
+	throttledIncr := timing.Throttle(incr, 60, map[string]bool{"leading": false, "name": true})
 	throttledIncr()
 	throttledIncr()
-	asserts.IntEquals( t, "Throttle does not trigger leading call 1", counter , 0)
-	var localWg sync.WaitGroup
-	localWg.Add(1)
-	Delay(func() T {
-		asserts.IntEquals( t, "Throttle does not trigger leading call 2", counter , 1)
-		localWg.Done()
-		return nil
-    }, 100)
-	localWg.Wait()
+	asserts.IntEquals(t, "Throttle does not trigger leading call 1", counter, 0)
+	clock.Advance(100 * time.Millisecond)
+	asserts.IntEquals(t, "Throttle does not trigger leading call 2", counter, 1)
 }
 
-
-func TestMoreThrottleDoesNotTriggerLeadingCallWhenLeadingIsFalse (t *testing.T){
-	counter := new(int)
-	incr := func(...T) T{ *counter += 1; return counter }
-	throttledIncr := Throttle(incr, 100, map[string]bool{"leading":false})
+func TestMoreThrottleDoesNotTriggerLeadingCallWhenLeadingIsFalse(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
+	counter := 0
+	incr := func(...T) T { counter += 1; return counter }
+	throttledIncr := timing.Throttle(incr, 100, map[string]bool{"leading": false})
 	throttledIncr()
-   Delay(func()T{ throttledIncr(); return *counter }, 50)
-   Delay(func()T{ throttledIncr(); return *counter }, 60)
-   Delay(func()T{ throttledIncr(); return *counter }, 200)
-   Delay(func()T{ throttledIncr(); return *counter }, 350)
-
-	var localWg sync.WaitGroup
-	localWg.Add(2)
-   Delay(func()T {
-		asserts.IntEquals(t, "More throttle, first delay, counter should be 1", *counter , 1)
-		localWg.Done()
-		return nil
-    }, 250)
-
-   Delay(func() T{
-		asserts.IntEquals(t, "More throttle, second delay, counter should be 2", *counter , 2)
-		localWg.Done()
-		return nil
-    }, 350)
-   asserts.IntEquals(t, "More throttle, counter still zero ", *counter , 0)
-	localWg.Wait()
-
-	/*select {
-		case <-time.After(1000):
-	}
-	fmt.Printf("end counter %v, now %v\n",*counter,time.Now().UnixNano() / 1000000);
-	*/
+	timing.Delay(func(...T) T { throttledIncr(); return counter }, 50)
+	timing.Delay(func(...T) T { throttledIncr(); return counter }, 60)
+	timing.Delay(func(...T) T { throttledIncr(); return counter }, 200)
+	timing.Delay(func(...T) T { throttledIncr(); return counter }, 350)
+	asserts.IntEquals(t, "More throttle, counter still zero ", counter, 0)
+	clock.Advance(250 * time.Millisecond)
+	asserts.IntEquals(t, "More throttle, first delay, counter should be 1", counter, 1)
+	clock.Advance(100 * time.Millisecond)
+	asserts.IntEquals(t, "More throttle, second delay, counter should be 2", counter, 2)
 }
 
-func TestOneMoreThrottleWithLeadingFalse(t *testing.T){
+func TestOneMoreThrottleWithLeadingFalse(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
 	counter := 0
-	incr := func(...T)T{ counter += 1; return counter }
-	throttledIncr := Throttle(incr, 100, map[string]bool{"leading": false})
+	incr := func(...T) T { counter += 1; return counter }
+	throttledIncr := timing.Throttle(incr, 100, map[string]bool{"leading": false})
 
-	time := Now()
-	for Now() - time < 350000000 {
+	time0 := timing.Now()
+	for timing.Now()-time0 < int64(350*time.Millisecond) {
 		throttledIncr()
+		clock.Advance(time.Millisecond)
 	}
 	asserts.Ok(t, "One More leading false throttle test, first check", counter <= 3)
-
-	DelayAndWait(func()T {
-		asserts.Ok(t, "One More leading false throttle test, second check", counter <= 4)
-		return nil
-	}, 200)
+	clock.Advance(200 * time.Millisecond)
+	asserts.Ok(t, "One More leading false throttle test, second check", counter <= 4)
 }
 
-func TestThrottleDoesNotTriggerTrailingCallWhenTrailingIsSettoFalse( t *testing.T){
+func TestThrottleDoesNotTriggerTrailingCallWhenTrailingIsSettoFalse(t *testing.T) {
+	clock := NewManualClock()
+	timing := WithClock(clock)
 	counter := 0
-	incr := func(...T)T{ counter += 1; return counter }
-	throttledIncr := Throttle(incr, 60, map[string]bool{"trailing": false})
+	incr := func(...T) T { counter += 1; return counter }
+	throttledIncr := timing.Throttle(incr, 60, map[string]bool{"trailing": false})
 
 	throttledIncr()
 	throttledIncr()
 	throttledIncr()
 
-	asserts.IntEquals(t, "TriggerTrailingCall 1", counter, 1 )
-   Delay(func()T{
-		asserts.IntEquals(t, "TriggerTrailingCall 2", counter, 1 )
-      throttledIncr()
-		throttledIncr()
-		asserts.IntEquals(t, "TriggerTrailingCall 3", counter, 2 )
+	asserts.IntEquals(t, "TriggerTrailingCall 1", counter, 1)
+	clock.Advance(96 * time.Millisecond)
+	asserts.IntEquals(t, "TriggerTrailingCall 2", counter, 1)
+	throttledIncr()
+	throttledIncr()
+	asserts.IntEquals(t, "TriggerTrailingCall 3", counter, 2)
+	clock.Advance(96 * time.Millisecond)
+	asserts.IntEquals(t, "TriggerTrailingCall 4", counter, 2)
+}
 
-      Delay(func()T{
-			asserts.IntEquals(t, "TriggerTrailingCall 4", counter, 2 )
-			return nil
-      }, 96)
+func TestDebouncer(t *testing.T) {
+	clock := NewManualClock()
+	calls := []T{}
+	record := func(args ...T) T {
+		calls = append(calls, args[0])
+		return len(calls)
+	}
+	d := NewDebouncer(record, 100*time.Millisecond, DebounceOptions{Clock: clock})
+	d.Call("a")
+	clock.Advance(50 * time.Millisecond)
+	d.Call("b")
+	asserts.True(t, "a call is pending", d.Pending())
+	clock.Advance(99 * time.Millisecond)
+	asserts.IntEquals(t, "still waiting", len(calls), 0)
+	clock.Advance(time.Millisecond)
+	asserts.Equals(t, "trailing call with the latest args", fmt.Sprint(calls), "[b]")
+	asserts.False(t, "nothing pending", d.Pending())
+
+	d.Call("c")
+	d.Cancel()
+	clock.Advance(time.Second)
+	asserts.Equals(t, "cancelled", fmt.Sprint(calls), "[b]")
+
+	d.Call("d")
+	asserts.IntEquals(t, "flush calls now", d.Flush().(int), 2)
+	asserts.Equals(t, "flushed", fmt.Sprint(calls), "[b d]")
+	clock.Advance(time.Second)
+	asserts.Equals(t, "nothing left after flush", fmt.Sprint(calls), "[b d]")
+
+	calls = []T{}
+	leading := NewDebouncer(record, 100*time.Millisecond, DebounceOptions{Leading: true, Clock: clock})
+	leading.Call("a")
+	leading.Call("b")
+	clock.Advance(time.Second)
+	asserts.Equals(t, "leading only", fmt.Sprint(calls), "[a]")
+
+	calls = []T{}
+	maxWait := NewDebouncer(record, 100*time.Millisecond, DebounceOptions{MaxWait: 250 * time.Millisecond, Clock: clock})
+	for i := 0; i < 10; i++ {
+		maxWait.Call(i)
+		clock.Advance(50 * time.Millisecond)
+	}
+	clock.Advance(time.Second)
+	asserts.Equals(t, "maxWait forces calls during a steady stream", fmt.Sprint(calls), "[4 9]")
+}
+
+func TestThrottler(t *testing.T) {
+	clock := NewManualClock()
+	calls := []T{}
+	record := func(args ...T) T {
+		calls = append(calls, args[0])
+		return len(calls)
+	}
+	th := NewThrottler(record, 100*time.Millisecond, DebounceOptions{Clock: clock})
+	for i := 0; i < 10; i++ {
+		th.Call(i)
+		clock.Advance(30 * time.Millisecond)
+	}
+	clock.Advance(time.Second)
+	asserts.Equals(t, "at most once per wait, leading and trailing", fmt.Sprint(calls), "[0 3 6 9]")
+
+	calls = []T{}
+	trailingOnly := NewThrottler(record, 100*time.Millisecond, DebounceOptions{Trailing: true, Clock: clock})
+	trailingOnly.Call("a")
+	trailingOnly.Call("b")
+	asserts.IntEquals(t, "no leading call", len(calls), 0)
+	clock.Advance(100 * time.Millisecond)
+	asserts.Equals(t, "trailing call", fmt.Sprint(calls), "[b]")
+}
+
+func TestDebouncerConcurrent(t *testing.T) {
+	clock := NewManualClock()
+	var mu sync.Mutex
+	calls := 0
+	d := NewDebouncer(func(...T) T {
+		mu.Lock()
+		calls += 1
+		mu.Unlock()
 		return nil
-    }, 96)
+	}, 100*time.Millisecond, DebounceOptions{Clock: clock})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.Call()
+			d.Pending()
+		}()
+	}
+	wg.Wait()
+	clock.Advance(time.Second)
+	asserts.IntEquals(t, "one trailing call for a burst from many goroutines", calls, 1)
+}
+
+// Gates for orderedTask: openGate is already open, and shutGate (nil) stays shut until the task is cancelled
+var openGate, shutGate = func() chan struct{} { c := make(chan struct{}); close(c); return c }(), chan struct{}(nil)
+
+// A task returning value, or err, once after is closed, giving up if its context is cancelled first.
+// It closes done (when not nil) as it finishes, so other tasks can be ordered after it.
+func orderedTask(value T, err error, after <-chan struct{}, done chan struct{}) Task {
+	return func(ctx context.Context) (T, error) {
+		if done != nil {
+			defer close(done)
+		}
+		select {
+		case <-after:
+			return value, err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
 }
 
-func TestWaitPlaceholder(t *testing.T){
-	globalWg.Wait()
+func TestParallel(t *testing.T) {
+	ctx := context.Background()
+	fastDone, mediumDone := make(chan struct{}), make(chan struct{})
+	results, err := Parallel(ctx, []Task{
+		orderedTask("slow", nil, mediumDone, nil),
+		orderedTask("fast", nil, openGate, fastDone),
+		orderedTask("medium", nil, fastDone, mediumDone),
+	}, 0)
+	asserts.Nil(t, "no error", err)
+	asserts.Equals(t, "results in task order", fmt.Sprint(results), "[slow fast medium]")
+
+	var mu sync.Mutex
+	running, most := 0, 0
+	started, release := make(chan struct{}), make(chan struct{})
+	counted := func(ctx context.Context) (T, error) {
+		mu.Lock()
+		running += 1
+		if running > most {
+			most = running
+		}
+		mu.Unlock()
+		started <- struct{}{}
+		<-release
+		mu.Lock()
+		running -= 1
+		mu.Unlock()
+		return nil, nil
+	}
+	go func() {
+		// let two tasks run together, then finish one at a time as each next one starts
+		<-started
+		<-started
+		for i := 0; i < 4; i++ {
+			release <- struct{}{}
+			<-started
+		}
+		release <- struct{}{}
+		release <- struct{}{}
+	}()
+	Parallel(ctx, []Task{counted, counted, counted, counted, counted, counted}, 2)
+	asserts.IntEquals(t, "at most limit tasks at once", most, 2)
+
+	errBroken := errors.New("broken")
+	results, err = Parallel(ctx, []Task{
+		orderedTask("slow", nil, shutGate, nil),
+		orderedTask(nil, errBroken, openGate, nil),
+	}, 0)
+	asserts.True(t, "the first error is returned", errors.Is(err, errBroken))
+	asserts.Equals(t, "with the task's index", err.Error(), "task 1: broken")
+	asserts.Nil(t, "and the rest are cancelled, rather than run to the end", results[0])
+
+	defer func() {
+		asserts.Equals(t, "panics are passed on", fmt.Sprint(recover()), "boom")
+	}()
+	Parallel(ctx, []Task{func(context.Context) (T, error) { panic("boom") }}, 0)
+}
+
+func TestSeries(t *testing.T) {
+	order := []int{}
+	step := func(n int, err error) Task {
+		return func(context.Context) (T, error) {
+			order = append(order, n)
+			return n * 10, err
+		}
+	}
+	results, err := Series(context.Background(), []Task{step(1, nil), step(2, nil), step(3, nil)})
+	asserts.Nil(t, "no error", err)
+	asserts.Equals(t, "results in order", fmt.Sprint(results), "[10 20 30]")
+
+	order = []int{}
+	results, err = Series(context.Background(), []Task{step(1, nil), step(2, errors.New("stop")), step(3, nil)})
+	asserts.Equals(t, "stops at the first error", err.Error(), "task 1: stop")
+	asserts.Equals(t, "later tasks aren't run", fmt.Sprint(order), "[1 2]")
+	asserts.Equals(t, "results so far", fmt.Sprint(results), "[10]")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Series(ctx, []Task{step(1, nil)})
+	asserts.True(t, "a done context stops the series", err == context.Canceled)
+}
+
+func TestWaterfall(t *testing.T) {
+	result, err := Waterfall(context.Background(), []WaterfallTask{
+		func(ctx context.Context, previous T) (T, error) { return 1, nil },
+		func(ctx context.Context, previous T) (T, error) { return previous.(int) + 2, nil },
+		func(ctx context.Context, previous T) (T, error) { return fmt.Sprint("total ", previous), nil },
+	})
+	asserts.Nil(t, "no error", err)
+	asserts.Equals(t, "each task gets the previous result", result.(string), "total 3")
+
+	_, err = Waterfall(context.Background(), []WaterfallTask{
+		func(ctx context.Context, previous T) (T, error) { return nil, errors.New("dry") },
+	})
+	asserts.Equals(t, "errors stop the waterfall", err.Error(), "task 0: dry")
+}
+
+func TestRace(t *testing.T) {
+	ctx := context.Background()
+	result, err := Race(ctx, []Task{
+		orderedTask("slow", nil, shutGate, nil),
+		orderedTask("fast", nil, openGate, nil),
+	})
+	asserts.Nil(t, "no error", err)
+	asserts.Equals(t, "the first to finish wins", result.(string), "fast")
+
+	errFast := errors.New("fast failure")
+	_, err = Race(ctx, []Task{
+		orderedTask("slow", nil, shutGate, nil),
+		orderedTask(nil, errFast, openGate, nil),
+	})
+	asserts.True(t, "even when it fails", err == errFast)
+
+	_, err = Race(ctx, nil)
+	asserts.True(t, "no tasks", err == ErrNoTasks)
+}
+
+func TestFirstSuccess(t *testing.T) {
+	ctx := context.Background()
+	failed := make(chan struct{})
+	result, err := FirstSuccess(ctx, []Task{
+		orderedTask(nil, errors.New("fast failure"), openGate, failed),
+		orderedTask("slow success", nil, failed, nil),
+		orderedTask("slowest", nil, shutGate, nil),
+	})
+	asserts.Nil(t, "no error", err)
+	asserts.Equals(t, "the first success wins", result.(string), "slow success")
+
+	errA, errB := errors.New("a"), errors.New("b")
+	bFailed := make(chan struct{})
+	_, err = FirstSuccess(ctx, []Task{
+		orderedTask(nil, errA, bFailed, nil),
+		orderedTask(nil, errB, openGate, bFailed),
+	})
+	asserts.Equals(t, "every error, in task order, when all fail", err.Error(), "all 2 tasks failed: a; b")
+	asserts.True(t, "which can be unwrapped", errors.Is(err, errA) && errors.Is(err, errB))
+}
+
+func TestAllSettled(t *testing.T) {
+	fastDone := make(chan struct{})
+	settled := AllSettled(context.Background(), []Task{
+		orderedTask("slow", nil, fastDone, nil),
+		orderedTask(nil, errors.New("failed"), openGate, nil),
+		orderedTask("fast", nil, openGate, fastDone),
+	})
+	asserts.Equals(t, "every outcome in task order", fmt.Sprint(settled), "[{slow <nil>} {<nil> failed} {fast <nil>}]")
 }
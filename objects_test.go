@@ -1,6 +1,7 @@
 package underscore
 
 import (
+	"encoding/json"
 	"github.com/markmontymark/asserts"
 	"fmt"
 	"math"
@@ -55,13 +56,13 @@ func TestInvert(t *testing.T) {
 func TestExtend(t *testing.T) {
 
 	asserts.Equals(t, "can extend an object with the attributes of another",
-		Extend(map[T]T{}, map[T]T{"a": "b"})["a"].(string), "b")
+		Extend(map[T]T{}, map[T]T{"a": "b"}).(map[T]T)["a"].(string), "b")
 
 	asserts.Equals(t, "properties in source override destination",
-		Extend(map[T]T{"a": "x"}, map[T]T{"a": "b"})["a"].(string), "b")
+		Extend(map[T]T{"a": "x"}, map[T]T{"a": "b"}).(map[T]T)["a"].(string), "b")
 
 	asserts.Equals(t, "properties not in source dont get overriden",
-		Extend(map[T]T{"x": "x"}, map[T]T{"a": "b"})["x"].(string), "x")
+		Extend(map[T]T{"x": "x"}, map[T]T{"a": "b"}).(map[T]T)["x"].(string), "x")
 
 	result := Extend(map[T]T{"x": "x"}, map[T]T{"a": "a"}, map[T]T{"b": "b"})
 	asserts.Equals(t, "can extend from multiple source objects",
@@ -78,7 +79,7 @@ func TestExtend(t *testing.T) {
 	result4 := map[T]T{}
 	result5 := Extend(result4, nil, 0, map[T]T{"a": 1})
 	asserts.IntEquals(t, "should not error on `null` or `undefined` sources",
-		result5["a"].(int), 1)
+		result5.(map[T]T)["a"].(int), 1)
 
 }
 
@@ -155,6 +156,377 @@ func TestClone(t *testing.T) {
 	asserts.NotEquals(t, "clone an array is shallow?", fmt.Sprint(cloneArray), fmt.Sprint(moe["lucky"]))
 }
 
+func TestOrderedMap(t *testing.T) {
+	om := NewOrderedMap("c", 3, "a", 1)
+	om.Set("b", 2).Set("c", 30)
+	asserts.Equals(t, "keys come back in insertion order", fmt.Sprint(om.Keys()), "[c a b]")
+	asserts.Equals(t, "resetting a key keeps its position", fmt.Sprint(om), "map[c:30 a:1 b:2]")
+	asserts.True(t, "deletes a key", om.Delete("a") && !om.Has("a") && om.Len() == 2)
+	asserts.False(t, "deleting a missing key is a no-op", om.Delete("zzz"))
+
+	var zero OrderedMap
+	zero.Set(1, "one")
+	asserts.Equals(t, "zero value is usable", fmt.Sprint(&zero), "map[1:one]")
+}
+
+func TestObjectFunctionsWithOrderedMap(t *testing.T) {
+	om := NewOrderedMap("z", 26, "a", 1, "m", 13)
+	asserts.Equals(t, "Keys", fmt.Sprint(Keys(om)), "[z a m]")
+	asserts.Equals(t, "Values", fmt.Sprint(Values(om)), "[26 1 13]")
+	asserts.Equals(t, "Pairs", fmt.Sprint(Pairs(om)), "[[z 26] [a 1] [m 13]]")
+	asserts.True(t, "Has", Has(om, "m") && !Has(om, "b"))
+	asserts.Equals(t, "Invert gives an OrderedMap", fmt.Sprint(Invert(om)), "map[26:z 1:a 13:m]")
+	asserts.Equals(t, "Pick gives an OrderedMap", fmt.Sprint(Pick(om, "m", "z")), "map[z:26 m:13]")
+	asserts.Equals(t, "Omit gives an OrderedMap", fmt.Sprint(Omit(om, "a")), "map[z:26 m:13]")
+	asserts.Equals(t, "Extend from an OrderedMap", fmt.Sprint(Extend(map[T]T{"b": 2}, om)),
+		fmt.Sprint(map[T]T{"b": 2, "z": 26, "a": 1, "m": 13}))
+	asserts.Equals(t, "Defaults from an OrderedMap", fmt.Sprint(Defaults(map[T]T{"a": 0}, om)),
+		fmt.Sprint(map[T]T{"a": 0, "z": 26, "m": 13}))
+	asserts.Equals(t, "Extend an OrderedMap", fmt.Sprint(Extend(om.Clone(), map[T]T{"b": 2})), "map[z:26 a:1 m:13 b:2]")
+	asserts.Equals(t, "Defaults for an OrderedMap", fmt.Sprint(Defaults(om.Clone(), NewOrderedMap("y", 25, "a", 0))),
+		"map[z:26 a:1 m:13 y:25]")
+
+	asserts.Equals(t, "Invert keeps order", fmt.Sprint(om.Invert()), "map[26:z 1:a 13:m]")
+	asserts.Equals(t, "Pick keeps order", fmt.Sprint(om.Pick("m", []T{"z"})), "map[z:26 m:13]")
+	asserts.Equals(t, "Omit keeps order", fmt.Sprint(om.Omit("a")), "map[z:26 m:13]")
+	asserts.Equals(t, "Extend appends new keys", fmt.Sprint(om.Clone().Extend(map[T]T{"b": 2}, NewOrderedMap("a", 100))),
+		"map[z:26 a:100 m:13 b:2]")
+	asserts.Equals(t, "Defaults appends missing keys", fmt.Sprint(om.Clone().Defaults(NewOrderedMap("a", 100, "y", 25))),
+		"map[z:26 a:1 m:13 y:25]")
+
+	clone := Clone(om).(*OrderedMap)
+	clone.Set("q", 17)
+	asserts.True(t, "Clone is a copy", clone.Len() == 4 && om.Len() == 3)
+
+	withFunc := NewOrderedMap("name", "moe", "greet", func(args ...T) T {
+		return "hi " + args[0].(*OrderedMap).values["name"].(string)
+	})
+	asserts.Equals(t, "Result calls functions", Result(withFunc, "greet").(string), "hi moe")
+
+	asserts.Equals(t, "chain returns an OrderedMap", fmt.Sprint(New(om).Chain().Omit("z").Invert().Value()), "map[1:a 13:m]")
+}
+
+func TestOrderedMapJSON(t *testing.T) {
+	doc := `{"zeta":1,"alpha":{"y":[1,{"b":2,"a":3}],"x":null},"mid":"s"}`
+	om := new(OrderedMap)
+	err := json.Unmarshal([]byte(doc), om)
+	asserts.Nil(t, "unmarshals", err)
+	asserts.Equals(t, "keys in document order", fmt.Sprint(om.Keys()), "[zeta alpha mid]")
+	alpha, _ := om.Get("alpha")
+	asserts.Equals(t, "nested objects are OrderedMaps", fmt.Sprint(alpha), "map[y:[1 map[b:2 a:3]] x:<nil>]")
+
+	out, err := json.Marshal(om)
+	asserts.Nil(t, "marshals", err)
+	asserts.Equals(t, "round trips in order", string(out), doc)
+
+	out2, _ := json.Marshal(NewOrderedMap(2, "two", 1, map[T]T{"k": []T{"v"}}))
+	asserts.Equals(t, "stringifies keys and native maps", string(out2), `{"2":"two","1":{"k":["v"]}}`)
+
+	asserts.True(t, "rejects non-objects", json.Unmarshal([]byte(`[1,2]`), new(OrderedMap)) != nil)
+}
+
+type cloneDeepPoint struct {
+	X, Y int
+	Tags []T
+	Next *cloneDeepPoint
+}
+
+func TestCloneDeep(t *testing.T) {
+	moe := map[T]T{"name": "moe", "lucky": []T{13, 27, []T{34}}, "stats": map[T]T{"age": 40}}
+	clone := CloneDeep(moe).(map[T]T)
+	clone["lucky"].([]T)[2].([]T)[0] = 101
+	clone["stats"].(map[T]T)["age"] = 41
+	asserts.Equals(t, "changes to nested arrays are not shared", fmt.Sprint(moe["lucky"]), "[13 27 [34]]")
+	asserts.IntEquals(t, "changes to nested maps are not shared", moe["stats"].(map[T]T)["age"].(int), 40)
+
+	p := &cloneDeepPoint{X: 1, Tags: []T{"a"}, Next: &cloneDeepPoint{Y: 2}}
+	p.Next.Next = p
+	pc := CloneDeep(p).(*cloneDeepPoint)
+	pc.Tags[0] = "b"
+	pc.Next.Y = 3
+	asserts.True(t, "structs and pointers are copied", p.Tags[0] == "a" && p.Next.Y == 2 && pc != p)
+	asserts.True(t, "cycles are preserved", pc.Next.Next == pc)
+
+	cyclic := map[T]T{"a": 1}
+	cyclic["self"] = cyclic
+	cc := CloneDeep(cyclic).(map[T]T)
+	cc["a"] = 2
+	asserts.IntEquals(t, "cyclic maps are copied", cyclic["a"].(int), 1)
+	asserts.IntEquals(t, "cyclic maps point at the copy", cc["self"].(map[T]T)["a"].(int), 2)
+
+	om := NewOrderedMap("b", []T{1}, "a", nil)
+	oc := CloneDeep(om).(*OrderedMap)
+	oc.values["b"].([]T)[0] = 2
+	asserts.Equals(t, "OrderedMaps are copied in order", fmt.Sprint(om, " ", oc), "map[b:[1] a:<nil>] map[b:[2] a:<nil>]")
+
+	asserts.Nil(t, "nil stays nil", CloneDeep(nil))
+	asserts.IntEquals(t, "scalars are returned", CloneDeep(5).(int), 5)
+	asserts.Equals(t, "chain", fmt.Sprint(New([]T{[]T{1}}).Chain().CloneDeep().Value()), "[[1]]")
+}
+
+func TestMerge(t *testing.T) {
+	a := map[T]T{"a": 1, "nested": map[T]T{"x": 1}}
+	b := map[T]T{"b": 2, "nested": map[T]T{"y": 2}}
+	merged := Merge(a, b)
+	asserts.Equals(t, "merges one level, later wins", fmt.Sprint(merged),
+		fmt.Sprint(map[T]T{"a": 1, "b": 2, "nested": map[T]T{"y": 2}}))
+	asserts.IntEquals(t, "doesn't change the first map", len(a), 2)
+}
+
+func TestMergeDeep(t *testing.T) {
+	defaults := map[T]T{"db": map[T]T{"host": "localhost", "port": 5432}, "tags": []T{"a", "b"}}
+	overlay := map[T]T{"db": map[T]T{"port": 6543}, "tags": []T{"b", "c"}, "debug": true}
+
+	merged := MergeDeep(defaults, overlay)
+	asserts.Equals(t, "nested maps are merged", fmt.Sprint(merged["db"]), "map[host:localhost port:6543]")
+	asserts.Equals(t, "arrays are replaced by default", fmt.Sprint(merged["tags"]), "[b c]")
+	asserts.True(t, "new keys are added", merged["debug"].(bool))
+
+	merged["db"].(map[T]T)["host"] = "changed"
+	asserts.Equals(t, "result shares nothing with the inputs", defaults["db"].(map[T]T)["host"].(string), "localhost")
+
+	asserts.Equals(t, "arrays can be appended", fmt.Sprint(MergeDeepWith(MergeArraysAppend, defaults, overlay)["tags"]), "[a b b c]")
+	asserts.Equals(t, "arrays can be unioned", fmt.Sprint(MergeDeepWith(MergeArraysUnion, defaults, overlay)["tags"]), "[a b c]")
+	asserts.Equals(t, "union compares deeply",
+		fmt.Sprint(MergeDeepWith(MergeArraysUnion, map[T]T{"l": []T{map[T]T{"k": 1}}}, map[T]T{"l": []T{map[T]T{"k": 1}, map[T]T{"k": 2}}})["l"]),
+		"[map[k:1] map[k:2]]")
+
+	ordered := MergeDeep(map[T]T{"o": NewOrderedMap("z", 1)}, map[T]T{"o": map[T]T{"a": 2}})
+	asserts.Equals(t, "nested OrderedMaps stay ordered", fmt.Sprint(ordered["o"]), "map[z:1 a:2]")
+	asserts.Equals(t, "chain", fmt.Sprint(New(defaults).Chain().MergeDeep(overlay).Value().(map[T]T)["db"]), "map[host:localhost port:6543]")
+}
+
+func TestGet(t *testing.T) {
+	doc := map[T]T{"users": []T{map[T]T{"name": "moe"}, map[T]T{"name": "curly"}}, "om": NewOrderedMap("k", "v")}
+	asserts.Equals(t, "dotted path", Get(doc, "users.1.name").(string), "curly")
+	asserts.Equals(t, "list path", Get(doc, []T{"users", 0, "name"}).(string), "moe")
+	asserts.Equals(t, "into an OrderedMap", Get(doc, "om.k").(string), "v")
+	asserts.Nil(t, "missing path", Get(doc, "users.5.name"))
+	asserts.Equals(t, "missing path with default", Get(doc, "nope.nope", "dflt").(string), "dflt")
+	asserts.Equals(t, "empty path is the object", fmt.Sprint(Get([]T{1}, "")), "[1]")
+	asserts.Equals(t, "chain", New(doc).Chain().Get("users.0.name").Value().(string), "moe")
+}
+
+func TestSet(t *testing.T) {
+	doc := map[T]T{"a": map[T]T{"b": 1}}
+	Set(doc, "a.c", 2)
+	asserts.Equals(t, "sets in place", fmt.Sprint(doc), "map[a:map[b:1 c:2]]")
+
+	Set(doc, "x.y.1.z", 3)
+	asserts.Equals(t, "creates intermediate maps and arrays", fmt.Sprint(doc["x"]), "map[y:[<nil> map[z:3]]]")
+
+	created := Set(nil, []T{0, "k"}, "v")
+	asserts.Equals(t, "creates from nil", fmt.Sprint(created), "[map[k:v]]")
+
+	withList := map[T]T{"l": []T{1, 2, 3}, "n": 1}
+	Set(withList, "l.foo", 9)
+	Set(withList, "l.-1.bar", 9)
+	asserts.Equals(t, "arrays aren't replaced for segments that aren't indexes", fmt.Sprint(withList), "map[l:[1 2 3] n:1]")
+	asserts.Equals(t, "or copied", fmt.Sprint(SetCopy(withList, "l.foo", 9)), "map[l:[1 2 3] n:1]")
+	Set(withList, "n.m", 2)
+	asserts.Equals(t, "scalars in the way are replaced", fmt.Sprint(withList["n"]), "map[m:2]")
+
+	config := &diffConfig{Name: "a"}
+	Set(config, "Name", "b")
+	Set(config, "Missing", "c")
+	asserts.Equals(t, "sets exported struct fields", fmt.Sprint([]T{Get(config, "Name"), Get(config, "Missing", "none")}), "[b none]")
+
+	orig := map[T]T{"a": map[T]T{"b": []T{1, 2}}, "other": map[T]T{"shared": true}}
+	copied := SetCopy(orig, "a.b.0", 10).(map[T]T)
+	asserts.Equals(t, "copy has the change", fmt.Sprint(copied["a"]), "map[b:[10 2]]")
+	asserts.Equals(t, "original is unchanged", fmt.Sprint(orig["a"]), "map[b:[1 2]]")
+	copied["other"].(map[T]T)["shared"] = false
+	asserts.False(t, "untouched branches are shared", orig["other"].(map[T]T)["shared"].(bool))
+
+	chained := New(map[T]T{"n": 1}).Chain().Set("m", 2).Value()
+	asserts.Equals(t, "chain", fmt.Sprint(chained), "map[m:2 n:1]")
+}
+
+func TestUpdate(t *testing.T) {
+	incr := func(v T) T {
+		if v == nil {
+			return 1
+		}
+		return v.(int) + 1
+	}
+	doc := map[T]T{"counts": map[T]T{"a": 1}}
+	Update(doc, "counts.a", incr)
+	Update(doc, "counts.b", incr)
+	asserts.Equals(t, "updates in place, nil for missing", fmt.Sprint(doc), "map[counts:map[a:2 b:1]]")
+
+	copied := UpdateCopy(doc, "counts.a", incr)
+	asserts.Equals(t, "copy has the change", fmt.Sprint(copied), "map[counts:map[a:3 b:1]]")
+	asserts.Equals(t, "original is unchanged", fmt.Sprint(doc), "map[counts:map[a:2 b:1]]")
+	asserts.Equals(t, "chain", fmt.Sprint(New(doc).Chain().Update("counts.b", incr).Get("counts.b").Value()), "2")
+}
+
+func TestUnset(t *testing.T) {
+	doc := map[T]T{"a": map[T]T{"b": 1, "c": 2}, "l": []T{1, 2, 3}}
+	Unset(doc, "a.b")
+	asserts.Equals(t, "removes a key", fmt.Sprint(doc["a"]), "map[c:2]")
+	doc = Unset(doc, "l.1").(map[T]T)
+	asserts.Equals(t, "removes an array element", fmt.Sprint(doc["l"]), "[1 3]")
+	Unset(doc, "x.y.z")
+	asserts.Nil(t, "missing paths are not created", doc["x"])
+
+	copied := UnsetCopy(doc, "a.c")
+	asserts.Equals(t, "copy has the change", fmt.Sprint(copied), "map[a:map[] l:[1 3]]")
+	asserts.Equals(t, "original is unchanged", fmt.Sprint(doc), "map[a:map[c:2] l:[1 3]]")
+	asserts.Equals(t, "chain", fmt.Sprint(New(doc).Chain().Unset("l").Value()), "map[a:map[c:2]]")
+}
+
+func TestFlattenMap(t *testing.T) {
+	doc := map[T]T{"a": map[T]T{"b": map[T]T{"c": 1}, "l": []T{"x", map[T]T{"y": 2}}}, "e": map[T]T{}, "n": nil}
+	asserts.Equals(t, "flattens with dotted keys and indexes", fmt.Sprint(FlattenMap(doc)),
+		"map[a.b.c:1 a.l.0:x a.l.1.y:2 e:map[] n:<nil>]")
+	asserts.Equals(t, "custom separator", fmt.Sprint(FlattenMap(doc, FlattenOptions{Separator: "/"})),
+		"map[a/b/c:1 a/l/0:x a/l/1/y:2 e:map[] n:<nil>]")
+	asserts.Equals(t, "limited depth", fmt.Sprint(FlattenMap(doc, FlattenOptions{MaxDepth: 2})),
+		"map[a.b:map[c:1] a.l:[x map[y:2]] e:map[] n:<nil>]")
+	asserts.Equals(t, "OrderedMaps", fmt.Sprint(FlattenMap(NewOrderedMap("k", NewOrderedMap("j", 1)))), "map[k.j:1]")
+	asserts.Equals(t, "chain", fmt.Sprint(New(doc).Chain().FlattenMap().UnflattenMap().Value()), fmt.Sprint(doc))
+}
+
+func TestUnflattenMap(t *testing.T) {
+	flat := map[string]T{"a.b.c": 1, "a.l.1.y": 2, "a.l.0": "x", "e": map[T]T{}}
+	asserts.Equals(t, "rebuilds maps and arrays", fmt.Sprint(UnflattenMap(flat)),
+		"map[a:map[b:map[c:1] l:[x map[y:2]]] e:map[]]")
+	asserts.Equals(t, "custom separator", fmt.Sprint(UnflattenMap(map[string]T{"a/b": 1}, FlattenOptions{Separator: "/"})),
+		"map[a:map[b:1]]")
+	asserts.Equals(t, "round trips", fmt.Sprint(FlattenMap(UnflattenMap(flat))), fmt.Sprint(flat))
+
+	ids := map[string]T{"ids.100": "x", "ids.7": "y"}
+	asserts.Equals(t, "numeric keys that aren't 0, 1, 2... stay a map", fmt.Sprint(UnflattenMap(ids)), "map[ids:map[100:x 7:y]]")
+	asserts.Equals(t, "and round trip", fmt.Sprint(FlattenMap(UnflattenMap(ids))), fmt.Sprint(ids))
+	asserts.Equals(t, "huge indexes aren't allocated", fmt.Sprint(UnflattenMap(map[string]T{"a.999999999": 1})), "map[a:map[999999999:1]]")
+	asserts.Equals(t, "nor are indexes that aren't written plainly", fmt.Sprint(UnflattenMap(map[string]T{"a.0": 1, "a.01": 2})), "map[a:map[0:1 01:2]]")
+	asserts.Equals(t, "nested paths win over values", fmt.Sprint(UnflattenMap(map[string]T{"a": 1, "a.b": 2})), "map[a:map[b:2]]")
+
+	hosts := map[T]T{"hosts": map[T]T{"example.com": map[T]T{"port": 80}}}
+	limited := FlattenOptions{MaxDepth: 2}
+	asserts.Equals(t, "keys past MaxDepth aren't split", fmt.Sprint(UnflattenMap(FlattenMap(hosts, limited), limited)), fmt.Sprint(hosts))
+
+	indexed := map[T]T{"m": map[T]T{"0": "a", "1": "b"}}
+	asserts.Equals(t, "maps keyed 0, 1, 2... come back as arrays", fmt.Sprint(UnflattenMap(FlattenMap(indexed))), "map[m:[a b]]")
+	asserts.Equals(t, "unless NoArrays is set", fmt.Sprint(UnflattenMap(FlattenMap(indexed), FlattenOptions{NoArrays: true})), fmt.Sprint(indexed))
+}
+
+type diffConfig struct {
+	Name  string
+	Ports []T
+	Next  *diffConfig
+	note  string
+}
+
+func TestDiff(t *testing.T) {
+	a := map[T]T{"name": "svc", "replicas": 2, "ports": []T{80, 443, 8080}, "env": map[T]T{"A": "1", "B": "2"}}
+	b := map[T]T{"name": "svc", "replicas": 3, "ports": []T{80, 8443}, "env": map[T]T{"A": "1", "C": "3"}}
+	asserts.Equals(t, "finds added, removed and changed paths", fmt.Sprint(Diff(a, b)),
+		"[{remove [env B] 2 <nil>} {add [env C] <nil> 3} {replace [ports 1] 443 8443} {remove [ports 2] 8080 <nil>} {replace [replicas] 2 3}]")
+	asserts.Equals(t, "no changes", fmt.Sprint(Diff(a, CloneDeep(a))), "[]")
+	asserts.Equals(t, "type changes are replaced", fmt.Sprint(Diff(map[T]T{"k": []T{1}}, map[T]T{"k": "1"})), "[{replace [k] [1] 1}]")
+
+	sa := diffConfig{Name: "a", Ports: []T{1}, note: "x"}
+	sb := diffConfig{Name: "b", Ports: []T{1, 2}, note: "y"}
+	asserts.Equals(t, "walks exported struct fields", fmt.Sprint(Diff(&sa, &sb)),
+		"[{replace [Name] a b} {add [Ports 1] <nil> 2}]")
+	next := &diffConfig{Name: "n"}
+	changes := Diff(diffConfig{Next: next}, diffConfig{})
+	asserts.True(t, "a nil pointer field is replaced", len(changes) == 1 && changes[0].Op == "replace" &&
+		changes[0].From == next && changes[0].Value.(*diffConfig) == nil)
+	changes = Diff(diffConfig{}, diffConfig{Next: next})
+	asserts.True(t, "from either side", len(changes) == 1 && changes[0].Op == "replace" &&
+		changes[0].From.(*diffConfig) == nil && changes[0].Value == next)
+	asserts.Equals(t, "nested nil pointers are equal", fmt.Sprint(Diff(diffConfig{Next: next}, diffConfig{Next: &diffConfig{Name: "n"}})), "[]")
+	asserts.Equals(t, "chain", fmt.Sprint(New([]T{1}).Chain().Diff([]T{2}).Value()), "[{replace [0] 1 2}]")
+
+	cyclicA, cyclicB := map[T]T{"n": 1}, map[T]T{"n": 2}
+	cyclicA["self"], cyclicB["self"] = cyclicA, cyclicB
+	asserts.Equals(t, "compares cyclic maps once", fmt.Sprint(Diff(cyclicA, cyclicB)), "[{replace [n] 1 2}]")
+	listA, listB := []T{1, nil}, []T{2, nil}
+	listA[1], listB[1] = listA, listB
+	asserts.Equals(t, "and cyclic arrays", fmt.Sprint(Diff(listA, listB)), "[{replace [0] 1 2}]")
+}
+
+func TestJSONPatch(t *testing.T) {
+	changes := Diff(map[T]T{"a/b": 1, "l": []T{1, 2}}, map[T]T{"a/b": nil, "l": []T{1}, "n": map[T]T{1: "one"}})
+	out, err := json.Marshal(JSONPatch(changes))
+	asserts.Nil(t, "marshals", err)
+	asserts.Equals(t, "converts to RFC 6902", string(out),
+		`[{"op":"replace","path":"/a~1b","value":null},{"op":"remove","path":"/l/1"},{"op":"add","path":"/n","value":{"1":"one"}}]`)
+}
+
+func TestPatch(t *testing.T) {
+	a := map[T]T{"name": "svc", "replicas": 2, "ports": []T{80, 443, 8080}, "env": map[T]T{"A": "1", "B": "2"}, 7: "seven"}
+	b := map[T]T{"name": "svc", "replicas": 3, "ports": []T{80, 8443}, "env": map[T]T{"A": "1", "C": "3"}, 7: "SEVEN"}
+	patched, err := Patch(a, JSONPatch(Diff(a, b)))
+	asserts.Nil(t, "applies a diff", err)
+	asserts.Equals(t, "diff then patch gets you b", fmt.Sprint(patched), fmt.Sprint(b))
+	asserts.Equals(t, "a is unchanged", fmt.Sprint(a["ports"]), "[80 443 8080]")
+
+	var ops []PatchOp
+	json.Unmarshal([]byte(`[
+		{"op":"add","path":"/ports/0","value":22},
+		{"op":"add","path":"/ports/-","value":9000},
+		{"op":"move","from":"/env/A","path":"/env/Z"},
+		{"op":"copy","from":"/ports","path":"/old"},
+		{"op":"test","path":"/replicas","value":2}
+	]`), &ops)
+	patched, err = Patch(a, ops)
+	asserts.Nil(t, "applies parsed ops", err)
+	asserts.Equals(t, "inserts into arrays", fmt.Sprint(Get(patched, "ports")), "[22 80 443 8080 9000]")
+	asserts.Equals(t, "moves", fmt.Sprint(Get(patched, "env")), "map[B:2 Z:1]")
+	asserts.Equals(t, "copies", fmt.Sprint(Get(patched, "old")), "[22 80 443 8080 9000]")
+
+	ops = nil
+	json.Unmarshal([]byte(`[
+		{"op":"add","path":"/db","value":{"hosts":["a"],"opts":{"tls":true}}},
+		{"op":"add","path":"/db/hosts/-","value":"b"},
+		{"op":"replace","path":"/db/opts/tls","value":false}
+	]`), &ops)
+	patched, err = Patch(map[T]T{}, ops)
+	asserts.Nil(t, "later ops reach into values added from JSON", err)
+	asserts.Equals(t, "which are added as map[T]T and []T", fmt.Sprint(patched), "map[db:map[hosts:[a b] opts:map[tls:false]]]")
+	out, _ := json.Marshal(JSONPatch(Diff(a, b)))
+	ops = nil
+	json.Unmarshal(out, &ops)
+	patched, err = Patch(a, ops)
+	asserts.Nil(t, "a diff round trips through JSON", err)
+	asserts.Equals(t, "and still gets you b", fmt.Sprint(patched), fmt.Sprint(b))
+
+	sa := diffConfig{Name: "a", Ports: []T{1}, Next: &diffConfig{Name: "x"}, note: "kept"}
+	sb := diffConfig{Name: "b", Ports: []T{1, 2}, Next: &diffConfig{Name: "y"}}
+	patched, err = Patch(sa, JSONPatch(Diff(sa, sb)))
+	asserts.Nil(t, "patches structs", err)
+	result := patched.(diffConfig)
+	asserts.Equals(t, "struct diff then patch gets you b", fmt.Sprint([]T{result.Name, result.Ports, result.Next.Name}), "[b [1 2] y]")
+	asserts.Equals(t, "unexported fields are kept", result.note, "kept")
+	asserts.Equals(t, "the struct is unchanged", fmt.Sprint([]T{sa.Name, sa.Ports, sa.Next.Name}), "[a [1] x]")
+	patched, err = Patch(&sa, JSONPatch(Diff(&sa, &sb)))
+	asserts.Nil(t, "patches struct pointers", err)
+	asserts.Equals(t, "into a copy", fmt.Sprint([]T{patched.(*diffConfig).Name, sa.Name}), "[b a]")
+
+	var counted struct{ Count int }
+	json.Unmarshal([]byte(`[{"op":"replace","path":"/Count","value":3}]`), &ops)
+	patched, err = Patch(counted, ops)
+	asserts.True(t, "converts JSON numbers to the field's type", err == nil && patched.(struct{ Count int }).Count == 3)
+	_, err = Patch(counted, []PatchOp{{Op: "replace", Path: "/Count", Value: "three"}})
+	asserts.True(t, "values that don't fit the field are errors", err != nil)
+	_, err = Patch(counted, []PatchOp{{Op: "add", Path: "/Other", Value: 1}})
+	asserts.True(t, "fields can't be added", err != nil)
+
+	_, err = Patch(a, []PatchOp{{Op: "test", Path: "/name", Value: "nope"}})
+	asserts.True(t, "failed tests are errors", err != nil)
+	_, err = Patch(a, []PatchOp{{Op: "remove", Path: "/missing"}})
+	asserts.True(t, "missing paths are errors", err != nil)
+	_, err = Patch(a, []PatchOp{{Op: "move", From: "/env", Path: "/env/x"}})
+	asserts.True(t, "can't move into a child", err != nil)
+	_, err = Patch(a, []PatchOp{{Op: "add", Path: "/nope/x", Value: 1}})
+	asserts.True(t, "add needs a parent", err != nil)
+	root, _ := Patch(a, []PatchOp{{Op: "replace", Path: "", Value: "root"}})
+	asserts.Equals(t, "replaces the root", root.(string), "root")
+}
+
 // TODO: missing TestIsEqual from objects.js
 // TODO: missing TestIsEmpty from objects.js
 // XXX: missing TestIsElement from objects.js - wont add
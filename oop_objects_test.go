@@ -254,9 +254,10 @@ func TestTapOOP(t *testing.T) {
 }
 
 func TestNowOOP(t *testing.T) {
-	diff := New().Chain().Now().Value().(int64) - time.Now().UnixNano()
+	before := time.Now().UnixNano()
+	now := New().Chain().Now().Value().(int64)
 	asserts.True(t, "Now called in OOP style, in a chain",
-		diff <= 0 && diff >= -500)
+		now >= before && now <= time.Now().UnixNano())
 }
 
 func TestPartitionOOP(t *testing.T) {
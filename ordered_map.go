@@ -0,0 +1,325 @@
+package underscore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// An insertion-ordered map, for when the order keys were first seen matters,
+// ie grouped reports, or JSON documents that should round-trip with their keys in place.
+// The Object functions (Keys, Values, Pairs, Invert, Extend, Pick, Omit, Defaults,
+// Clone, Has, Result) accept an *OrderedMap wherever they accept a map[T]T, and given one,
+// Invert, Extend, Pick, Omit, Defaults, GroupBy, IndexBy and CountBy return an *OrderedMap.
+// The zero value is an empty map ready to use.
+type OrderedMap struct {
+	keys   []T
+	values map[T]T
+}
+
+// Create an OrderedMap, optionally seeded with alternating key, value arguments,
+// ie NewOrderedMap("a", 1, "b", 2) -> map[a:1 b:2]
+func NewOrderedMap(kvpairs ...T) *OrderedMap {
+	this := new(OrderedMap)
+	for i := 0; i+1 < len(kvpairs); i += 2 {
+		this.Set(kvpairs[i], kvpairs[i+1])
+	}
+	return this
+}
+
+// Set a key's value.  New keys are appended, existing keys keep their position.
+// Returns the map to allow calls to be strung together.
+func (this *OrderedMap) Set(key T, value T) *OrderedMap {
+	if this.values == nil {
+		this.values = make(map[T]T)
+	}
+	if _, ok := this.values[key]; !ok {
+		this.keys = append(this.keys, key)
+	}
+	this.values[key] = value
+	return this
+}
+
+// Get a key's value, and whether the key was present
+func (this *OrderedMap) Get(key T) (T, bool) {
+	if this == nil || this.values == nil {
+		return nil, false
+	}
+	v, ok := this.values[key]
+	return v, ok
+}
+
+// Is the key present in the map?
+func (this *OrderedMap) Has(key T) bool {
+	_, ok := this.Get(key)
+	return ok
+}
+
+// Remove a key from the map, returns whether the key was present
+func (this *OrderedMap) Delete(key T) bool {
+	if !this.Has(key) {
+		return false
+	}
+	delete(this.values, key)
+	for i, k := range this.keys {
+		if k == key {
+			this.keys = append(this.keys[:i:i], this.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// The number of keys in the map
+func (this *OrderedMap) Len() int {
+	if this == nil {
+		return 0
+	}
+	return len(this.keys)
+}
+
+// Retrieve the keys, in insertion order
+func (this *OrderedMap) Keys() []T {
+	retval := make([]T, this.Len())
+	if this != nil {
+		copy(retval, this.keys)
+	}
+	return retval
+}
+
+// Retrieve the values, in insertion order of their keys
+func (this *OrderedMap) Values() []T {
+	retval := make([]T, 0, this.Len())
+	for _, key := range this.Keys() {
+		retval = append(retval, this.values[key])
+	}
+	return retval
+}
+
+// Convert the map into a list of `[key, value]` pairs, in insertion order
+func (this *OrderedMap) Pairs() []T {
+	retval := make([]T, 0, this.Len())
+	for _, key := range this.Keys() {
+		retval = append(retval, []T{key, this.values[key]})
+	}
+	return retval
+}
+
+// Iterate over the map in insertion order, with the same signature Each uses for maps,
+// ie (val T, key T, obj *OrderedMap).  Return eachBreak (true) to stop iterating.
+func (this *OrderedMap) Each(iterator eachlistiterator) {
+	for _, key := range this.Keys() {
+		if iterator(this.values[key], key, this) == eachBreak {
+			return
+		}
+	}
+}
+
+// A native map[T]T with the same keys and values, order is lost
+func (this *OrderedMap) ToMap() map[T]T {
+	retval := make(map[T]T, this.Len())
+	for _, key := range this.Keys() {
+		retval[key] = this.values[key]
+	}
+	return retval
+}
+
+// A shallow copy of the map
+func (this *OrderedMap) Clone() *OrderedMap {
+	return new(OrderedMap).Extend(this)
+}
+
+// Return a new map with keys and values swapped, in the original key order
+func (this *OrderedMap) Invert() *OrderedMap {
+	retval := new(OrderedMap)
+	this.Each(func(value, key, list T) bool {
+		retval.Set(value, key)
+		return eachContinue
+	})
+	return retval
+}
+
+// Copy all of the properties of the passed-in maps (map[T]T or *OrderedMap) onto this one.
+// New keys are appended in the order they're found, see func Extend
+func (this *OrderedMap) Extend(args ...T) *OrderedMap {
+	for _, objToCopy := range args {
+		if !IsMap(objToCopy) && !IsOrderedMap(objToCopy) {
+			continue
+		}
+		Each(objToCopy, func(v, k, list T) bool {
+			this.Set(k, v)
+			return eachContinue
+		})
+	}
+	return this
+}
+
+// Fill in missing keys from the passed-in maps (map[T]T or *OrderedMap), first one wins.
+// See func Defaults
+func (this *OrderedMap) Defaults(args ...T) *OrderedMap {
+	for _, val := range args {
+		if !IsMap(val) && !IsOrderedMap(val) {
+			continue
+		}
+		Each(val, func(v, k, list T) bool {
+			if !this.Has(k) {
+				this.Set(k, v)
+			}
+			return eachContinue
+		})
+	}
+	return this
+}
+
+// Return a copy of the map only containing the whitelisted keys, in the original key order.
+// See func Pick
+func (this *OrderedMap) Pick(keysToKeep ...T) *OrderedMap {
+	keysToKeep = Flatten(keysToKeep, true)
+	retval := new(OrderedMap)
+	this.Each(func(v, k, list T) bool {
+		if Contains(keysToKeep, k) {
+			retval.Set(k, v)
+		}
+		return eachContinue
+	})
+	return retval
+}
+
+// Return a copy of the map without the blacklisted keys, in the original key order.
+// See func Omit
+func (this *OrderedMap) Omit(keysToRemove ...T) *OrderedMap {
+	keysToRemove = Flatten(keysToRemove, true)
+	retval := new(OrderedMap)
+	this.Each(func(v, k, list T) bool {
+		if !Contains(keysToRemove, k) {
+			retval.Set(k, v)
+		}
+		return eachContinue
+	})
+	return retval
+}
+
+// Stringify like fmt does for a native map, but in insertion order, ie map[b:1 a:2]
+func (this *OrderedMap) String() string {
+	var buf bytes.Buffer
+	buf.WriteString("map[")
+	for i, key := range this.Keys() {
+		if i > 0 {
+			buf.WriteString(" ")
+		}
+		fmt.Fprintf(&buf, "%v:%v", key, this.values[key])
+	}
+	buf.WriteString("]")
+	return buf.String()
+}
+
+// Implement json.Marshaler, writing keys in insertion order.
+// Non-string keys are stringified with fmt.Sprint, as JSON only has string keys.
+func (this *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, key := range this.Keys() {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		k, err := json.Marshal(fmt.Sprint(key))
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(jsonValue(this.values[key]))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteString(":")
+		buf.Write(v)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// Implement json.Unmarshaler, keeping keys in document order.
+// Nested objects decode to *OrderedMap, arrays to []T, and numbers to float64
+// as they would with encoding/json.
+func (this *OrderedMap) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("OrderedMap: expected a JSON object, got %v", tok)
+	}
+	decoded, err := decodeJSONObject(dec)
+	if err != nil {
+		return err
+	}
+	this.keys, this.values = decoded.keys, decoded.values
+	return nil
+}
+
+// Internal function to decode the rest of a JSON object, the opening '{' having been read
+func decodeJSONObject(dec *json.Decoder) (*OrderedMap, error) {
+	retval := new(OrderedMap)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeJSONValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		retval.Set(tok.(string), value)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return retval, nil
+}
+
+// Internal function to decode the next JSON value, objects as *OrderedMap
+func decodeJSONValue(dec *json.Decoder) (T, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	if delim == '{' {
+		return decodeJSONObject(dec)
+	}
+	list := make([]T, 0)
+	for dec.More() {
+		value, err := decodeJSONValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Internal function to make native map[T]T values, which encoding/json can't handle, marshalable
+func jsonValue(value T) T {
+	if IsMap(value) {
+		retval := make(map[string]T, len(value.(map[T]T)))
+		for k, v := range value.(map[T]T) {
+			retval[fmt.Sprint(k)] = jsonValue(v)
+		}
+		return retval
+	}
+	if IsArray(value) {
+		retval := make([]T, len(value.([]T)))
+		for i, v := range value.([]T) {
+			retval[i] = jsonValue(v)
+		}
+		return retval
+	}
+	return value
+}
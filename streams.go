@@ -0,0 +1,324 @@
+package underscore
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Stream Functions, channel versions of the Collection Functions for unbounded input,
+// ie log lines or queue messages.
+// Each operator reads its input in its own goroutine and returns an unbuffered output
+// channel, so a slow reader holds up the whole pipeline rather than buffering without limit.
+// Outputs are closed when the input is, or as soon as ctx is done.
+// Iterators have the same signatures as for Map and Filter, so they can be shared between
+// slices and streams: they're given the element, its index in the stream, and the input channel.
+
+// Internal function to send on a stream, returns false if ctx was done first
+func streamSend(ctx context.Context, out chan<- T, value T) bool {
+	select {
+	case out <- value:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Stream the elements of a list
+func StreamFrom(ctx context.Context, list []T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for _, value := range list {
+			if !streamSend(ctx, out, value) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Read a stream until it's closed, or ctx is done, returning the elements read
+func StreamCollect(ctx context.Context, in <-chan T) []T {
+	retval := make([]T, 0)
+	for {
+		select {
+		case value, ok := <-in:
+			if !ok {
+				return retval
+			}
+			retval = append(retval, value)
+		case <-ctx.Done():
+			return retval
+		}
+	}
+}
+
+// Produce a new stream of values by mapping each element through an iterator, see func Map
+func StreamMap(ctx context.Context, in <-chan T, iterator func(T, T, T) T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for i := 0; ; i++ {
+			select {
+			case value, ok := <-in:
+				if !ok || !streamSend(ctx, out, iterator(value, i, in)) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Pass on only the elements the iterator returns true for, see func Filter
+func StreamFilter(ctx context.Context, in <-chan T, iterator eachlistiterator) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for i := 0; ; i++ {
+			select {
+			case value, ok := <-in:
+				if !ok {
+					return
+				}
+				if iterator(value, i, in) && !streamSend(ctx, out, value) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Group elements into lists of up to size.  With maxWait above 0, a partial
+// list is passed on once maxWait has passed since its first element, so quiet streams still flow.
+// The last, partial, list is passed on when the input closes.  Times are measured by DefaultClock,
+// see WithClock for another clock.
+func StreamBatch(ctx context.Context, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	return WithClock(nil).StreamBatch(ctx, in, size, maxWait)
+}
+
+// StreamBatch on this Timing's clock, see func StreamBatch
+func (this Timing) StreamBatch(ctx context.Context, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	clock := clockOrDefault(this.clock)
+	if size < 1 {
+		size = 1
+	}
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		var batch []T
+		var timer Timer
+		var timeout <-chan time.Time
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+			select {
+			case out <- batch:
+				batch = nil
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case value, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				batch = append(batch, value)
+				if len(batch) == 1 && maxWait > 0 {
+					timer = clock.NewTimer(maxWait)
+					timeout = timer.C()
+				}
+				if len(batch) >= size && !flush() {
+					return
+				}
+			case <-timeout:
+				timer, timeout = nil, nil
+				if !flush() {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Pass on at most one element per wait: the first straight away, then the latest of those
+// that arrived during the wait, once it's over, see func Throttle.  Elements in between are dropped.
+// Times are measured by DefaultClock, see WithClock for another clock.
+func StreamThrottle(ctx context.Context, in <-chan T, wait time.Duration) <-chan T {
+	return WithClock(nil).StreamThrottle(ctx, in, wait)
+}
+
+// StreamThrottle on this Timing's clock, see func StreamThrottle
+func (this Timing) StreamThrottle(ctx context.Context, in <-chan T, wait time.Duration) <-chan T {
+	clock := clockOrDefault(this.clock)
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		var timer Timer
+		var timeout <-chan time.Time
+		var latest T
+		hasLatest := false
+		for {
+			select {
+			case value, ok := <-in:
+				if !ok {
+					if hasLatest {
+						streamSend(ctx, out, latest)
+					}
+					if timer != nil {
+						timer.Stop()
+					}
+					return
+				}
+				if timeout != nil {
+					latest, hasLatest = value, true
+					continue
+				}
+				if !streamSend(ctx, out, value) {
+					return
+				}
+				timer = clock.NewTimer(wait)
+				timeout = timer.C()
+			case <-timeout:
+				timer, timeout = nil, nil
+				if hasLatest {
+					if !streamSend(ctx, out, latest) {
+						return
+					}
+					latest, hasLatest = nil, false
+					timer = clock.NewTimer(wait)
+					timeout = timer.C()
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Pass on an element only once wait has passed without another arriving, see func Debounce.
+// A pending element is passed on when the input closes.  Times are measured by DefaultClock,
+// see WithClock for another clock.
+func StreamDebounce(ctx context.Context, in <-chan T, wait time.Duration) <-chan T {
+	return WithClock(nil).StreamDebounce(ctx, in, wait)
+}
+
+// StreamDebounce on this Timing's clock, see func StreamDebounce
+func (this Timing) StreamDebounce(ctx context.Context, in <-chan T, wait time.Duration) <-chan T {
+	clock := clockOrDefault(this.clock)
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		var timer Timer
+		var timeout <-chan time.Time
+		var pending T
+		for {
+			select {
+			case value, ok := <-in:
+				if !ok {
+					if timer != nil {
+						timer.Stop()
+						streamSend(ctx, out, pending)
+					}
+					return
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				pending = value
+				timer = clock.NewTimer(wait)
+				timeout = timer.C()
+			case <-timeout:
+				timer, timeout = nil, nil
+				if !streamSend(ctx, out, pending) {
+					return
+				}
+				pending = nil
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Combine several streams into one, in the order elements arrive.
+// The output closes once every input has.
+func StreamMerge(ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Add(1)
+		go func(in <-chan T) {
+			defer wg.Done()
+			for {
+				select {
+				case value, ok := <-in:
+					if !ok || !streamSend(ctx, out, value) {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(in)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Split a stream into n streams that each get every element.  Every output has to be
+// read: an element is only passed on to all of them before the next one is read.
+func StreamTee(ctx context.Context, in <-chan T, n int) []<-chan T {
+	outs := make([]chan T, n)
+	retval := make([]<-chan T, n)
+	for i := range outs {
+		outs[i] = make(chan T)
+		retval[i] = outs[i]
+	}
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+		for {
+			select {
+			case value, ok := <-in:
+				if !ok {
+					return
+				}
+				for _, out := range outs {
+					if !streamSend(ctx, out, value) {
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return retval
+}
@@ -0,0 +1,229 @@
+package underscore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// A unit of work for Parallel, Series, Race and friends, in the style of node's async library.
+// Tasks should give up when ctx is done, as it will be once the outcome is decided.
+type Task func(ctx context.Context) (T, error)
+
+// A task for Waterfall, given the previous task's result
+type WaterfallTask func(ctx context.Context, previous T) (T, error)
+
+// The outcome of a task run by AllSettled, either a value or an error
+type Settled struct {
+	Value T
+	Err   error
+}
+
+// The errors from every task, in task order, when none of them succeeded, see FirstSuccess
+type TaskErrors []error
+
+func (this TaskErrors) Error() string {
+	messages := make([]string, len(this))
+	for i, err := range this {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("all %d tasks failed: %s", len(this), strings.Join(messages, "; "))
+}
+
+func (this TaskErrors) Unwrap() []error {
+	return this
+}
+
+// Returned by Race and FirstSuccess when given no tasks
+var ErrNoTasks = errors.New("no tasks")
+
+// Internal type, what became of a task run in its own goroutine
+type taskOutcome struct {
+	index    int
+	value    T
+	err      error
+	finished bool
+	panicked T
+}
+
+// Internal function to run a task, catching a panic so it can be passed on to the caller
+func runTask(ctx context.Context, index int, task Task) (outcome taskOutcome) {
+	outcome.index = index
+	defer func() {
+		if !outcome.finished {
+			outcome.panicked = recover()
+		}
+	}()
+	outcome.value, outcome.err = task(ctx)
+	outcome.finished = true
+	return outcome
+}
+
+// Run the tasks concurrently, at most limit at once (0 for no limit), and return their
+// results in task order.  The first error cancels the other tasks' context and is returned,
+// wrapped with the task's index.  If a task panics, the panic is passed on once the others are done.
+func Parallel(ctx context.Context, tasks []Task, limit int) ([]T, error) {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if limit <= 0 || limit > len(tasks) {
+		limit = len(tasks)
+	}
+	results := make([]T, len(tasks))
+	var mu sync.Mutex
+	var firstErr error
+	var panicked *taskOutcome
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, task Task) {
+			defer wg.Done()
+			outcome := runTask(ctx, i, task)
+			<-slots
+			mu.Lock()
+			defer mu.Unlock()
+			if !outcome.finished {
+				if panicked == nil {
+					panicked = &outcome
+				}
+				cancel()
+			} else if outcome.err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("task %d: %w", i, outcome.err)
+				}
+				cancel()
+			}
+			results[i] = outcome.value
+		}(i, task)
+	}
+	wg.Wait()
+	if panicked != nil {
+		panic(panicked.panicked)
+	}
+	if firstErr == nil && parent.Err() != nil {
+		return results, parent.Err()
+	}
+	return results, firstErr
+}
+
+// Run the tasks one after another, stopping at the first error, which is returned
+// wrapped with the task's index.  The results so far are returned in task order.
+func Series(ctx context.Context, tasks []Task) ([]T, error) {
+	results := make([]T, 0, len(tasks))
+	for i, task := range tasks {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		result, err := task(ctx)
+		if err != nil {
+			return results, fmt.Errorf("task %d: %w", i, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Run the tasks one after another, each given the previous one's result (nil for the first),
+// and return the last result.  Stops at the first error, which is returned wrapped with the task's index.
+func Waterfall(ctx context.Context, tasks []WaterfallTask) (T, error) {
+	var previous T
+	for i, task := range tasks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, err := task(ctx, previous)
+		if err != nil {
+			return nil, fmt.Errorf("task %d: %w", i, err)
+		}
+		previous = result
+	}
+	return previous, nil
+}
+
+// Internal function to start every task in its own goroutine, returning a channel of their outcomes
+func startTasks(ctx context.Context, tasks []Task) <-chan taskOutcome {
+	outcomes := make(chan taskOutcome, len(tasks))
+	for i, task := range tasks {
+		go func(i int, task Task) {
+			outcomes <- runTask(ctx, i, task)
+		}(i, task)
+	}
+	return outcomes
+}
+
+// Run the tasks concurrently, and return the result or error of whichever finishes first,
+// like Promise.race.  The others' context is cancelled, Race doesn't wait for them to stop.
+func Race(ctx context.Context, tasks []Task) (T, error) {
+	if len(tasks) == 0 {
+		return nil, ErrNoTasks
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	select {
+	case outcome := <-startTasks(ctx, tasks):
+		if !outcome.finished {
+			panic(outcome.panicked)
+		}
+		return outcome.value, outcome.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Run the tasks concurrently, and return the result of whichever succeeds first, like
+// Promise.any (Any being taken by the collection function).  The others' context is cancelled.
+// If every task fails, their errors are returned as TaskErrors.
+func FirstSuccess(ctx context.Context, tasks []Task) (T, error) {
+	if len(tasks) == 0 {
+		return nil, ErrNoTasks
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	outcomes := startTasks(ctx, tasks)
+	errs := make(TaskErrors, len(tasks))
+	for range tasks {
+		select {
+		case outcome := <-outcomes:
+			if !outcome.finished {
+				panic(outcome.panicked)
+			}
+			if outcome.err == nil {
+				return outcome.value, nil
+			}
+			errs[outcome.index] = outcome.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, errs
+}
+
+// Run the tasks concurrently, wait for all of them, and return each one's outcome in task
+// order, like Promise.allSettled.  Errors don't stop the other tasks.
+// If a task panics, the panic is passed on once the others are done.
+func AllSettled(ctx context.Context, tasks []Task) []Settled {
+	settled := make([]Settled, len(tasks))
+	outcomes := startTasks(ctx, tasks)
+	var panicked *taskOutcome
+	for range tasks {
+		outcome := <-outcomes
+		if !outcome.finished && panicked == nil {
+			panicked = &outcome
+		}
+		settled[outcome.index] = Settled{Value: outcome.value, Err: outcome.err}
+	}
+	if panicked != nil {
+		panic(panicked.panicked)
+	}
+	return settled
+}
@@ -0,0 +1,1096 @@
+package underscore
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// template_.template(templateString, [settings])
+// Compiles JavaScript-flavored templates into functions that can be evaluated for rendering.
+// Useful for rendering complicated bits of HTML from JSON data sources. Templates can
+// interpolate values, using <%= … %>, as well as execute arbitrary code, with <% … %>.
+// If you wish to interpolate a value, and have it be HTML-escaped, use <%- … %>
+//
+// As Go can't run JavaScript, the code in <% … %> blocks is a small subset of it:
+// literals, variables and property access (a.b, a[0], list.length), arithmetic, comparison,
+// logical and ternary operators, function calls and `function (a, b) { … }` literals,
+// and the statements var, if/else, for (…;…;…), for (k in obj), return and print(…).
+// Rendering gives up with an error after a million loop iterations and function calls,
+// or with functions nested 1000 deep, so a runaway loop can't hang it.
+// That covers the usual Underscore templates, ie
+//
+//	<% _.each(people, function(name) { %> <li><%- name %></li> <% }); %>
+
+// Regular expressions that mark the evaluate, interpolate and escape blocks of a template,
+// each with a single capture group for the code inside
+type TemplateSettings struct {
+	Evaluate    *regexp.Regexp
+	Interpolate *regexp.Regexp
+	Escape      *regexp.Regexp
+	// If set, the data is only reachable through this name, rather than its
+	// keys (or fields) being variables themselves
+	Variable string
+}
+
+// By default, Template uses ERB-style delimiters, change these to use alternative
+// delimiters, like Underscore's _.templateSettings, ie Mustache-style {{ … }}
+var TemplateDefaults = TemplateSettings{
+	Evaluate:    regexp.MustCompile(`<%([\s\S]+?)%>`),
+	Interpolate: regexp.MustCompile(`<%=([\s\S]+?)%>`),
+	Escape:      regexp.MustCompile(`<%-([\s\S]+?)%>`),
+}
+
+// Functions available to every template as properties of `_`, ie _.escape(name)
+var TemplateHelpers = map[string]T{
+	"each": func(args ...T) T {
+		fn := templateFunc(args[1])
+		Each(args[0], func(value, index, list T) bool {
+			fn(value, index, list)
+			return eachContinue
+		})
+		return nil
+	},
+	"map": func(args ...T) T {
+		fn := templateFunc(args[1])
+		results := make([]T, 0)
+		Each(args[0], func(value, index, list T) bool {
+			results = append(results, fn(value, index, list))
+			return eachContinue
+		})
+		return results
+	},
+	"filter": func(args ...T) T {
+		fn := templateFunc(args[1])
+		results := make([]T, 0)
+		Each(args[0], func(value, index, list T) bool {
+			if templateTruthy(fn(value, index, list)) {
+				results = append(results, value)
+			}
+			return eachContinue
+		})
+		return results
+	},
+	"size":    func(args ...T) T { return Size(args[0]) },
+	"keys":    func(args ...T) T { return Keys(args[0]) },
+	"values":  func(args ...T) T { return Values(args[0]) },
+	"has":     func(args ...T) T { return Has(args[0], args[1]) },
+	"isEmpty": func(args ...T) T { return IsEmpty(args[0]) },
+	"contains": func(args ...T) T {
+		return Any(ToArray(args[0]), func(value, index, list T) bool { return templateEquals(value, args[1]) })
+	},
+	"first": func(args ...T) T { return First(ToArray(args[0])) },
+	"last": func(args ...T) T {
+		list := ToArray(args[0])
+		if len(list) == 0 {
+			return nil
+		}
+		return list[len(list)-1]
+	},
+	"range": func(args ...T) T {
+		ints := make([]int, len(args))
+		for i, arg := range args {
+			f, _ := toFloat64(arg)
+			ints[i] = int(f)
+		}
+		return Range(ints...)
+	},
+	"escape":   func(args ...T) T { return Escape(templateString(args[0])) },
+	"unescape": func(args ...T) T { return Unescape(templateString(args[0])) },
+}
+
+// Compile a template into a function that renders it for some data, a map[T]T,
+// map[string]T, *OrderedMap or struct whose keys (or exported fields) become the
+// template's variables.  Settings missing from opt_settings come from TemplateDefaults.
+//
+//	compiled, _ := Template("hello: <%= name %>")
+//	compiled(map[T]T{"name": "moe"}) -> "hello: moe"
+func Template(text string, opt_settings ...TemplateSettings) (func(data T) (string, error), error) {
+	settings := TemplateDefaults
+	if len(opt_settings) > 0 {
+		if opt_settings[0].Evaluate != nil {
+			settings.Evaluate = opt_settings[0].Evaluate
+		}
+		if opt_settings[0].Interpolate != nil {
+			settings.Interpolate = opt_settings[0].Interpolate
+		}
+		if opt_settings[0].Escape != nil {
+			settings.Escape = opt_settings[0].Escape
+		}
+		settings.Variable = opt_settings[0].Variable
+	}
+
+	body, err := compileTemplate(text, settings)
+	if err != nil {
+		return nil, err
+	}
+	return func(data T) (rendered string, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				rendered, err = "", fmt.Errorf("Template: %v", r)
+			}
+		}()
+		render := &templateRender{data: data, variable: settings.Variable}
+		render.exec(body, &templateScope{vars: map[string]T{}})
+		return render.out.String(), nil
+	}, nil
+}
+
+// Internal function to split a template into text and code tokens, then parse them
+func compileTemplate(text string, settings TemplateSettings) (body []templateNode, err error) {
+	matcher := regexp.MustCompile(settings.Escape.String() + "|" +
+		settings.Interpolate.String() + "|" + settings.Evaluate.String() + "|$")
+	tokens := make([]templateToken, 0)
+	index := 0
+	for _, match := range matcher.FindAllStringSubmatchIndex(text, -1) {
+		if match[0] > index {
+			tokens = append(tokens, templateToken{kind: templateText, text: text[index:match[0]]})
+		}
+		index = match[1]
+		switch {
+		case match[2] >= 0:
+			tokens = append(tokens, templateToken{kind: templateEscape, text: text[match[2]:match[3]]})
+		case match[4] >= 0:
+			tokens = append(tokens, templateToken{kind: templateInterpolate, text: text[match[4]:match[5]]})
+		case match[6] >= 0:
+			code, err := lexTemplateCode(text[match[6]:match[7]])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, code...)
+		}
+	}
+	tokens = append(tokens, templateToken{kind: templateEOF})
+
+	defer func() {
+		if r := recover(); r != nil {
+			body, err = nil, fmt.Errorf("Template: %v", r)
+		}
+	}()
+	p := &templateParser{tokens: tokens}
+	body = p.statements()
+	if !p.at(templateEOF, "") {
+		p.fail("unexpected %q", p.peek().text)
+	}
+	return body, nil
+}
+
+// Kinds of templateToken
+const (
+	templateEOF = iota
+	templateText
+	templateInterpolate
+	templateEscape
+	templateNum
+	templateStr
+	templateIdent
+	templatePunct
+)
+
+// A piece of a template: literal text, an interpolated expression, or a token of evaluated code
+type templateToken struct {
+	kind int
+	text string
+	num  T
+}
+
+// Punctuation understood in template code, longest first
+var templatePuncts = []string{"===", "!==", "==", "!=", "<=", ">=", "&&", "||", "++", "--", "+=", "-=",
+	"(", ")", "{", "}", "[", "]", ",", ";", ".", ":", "?", "!", "<", ">", "+", "-", "*", "/", "%", "="}
+
+// Internal function to split template code into tokens
+func lexTemplateCode(code string) ([]templateToken, error) {
+	tokens := make([]templateToken, 0)
+	for i := 0; i < len(code); {
+		c, size := utf8.DecodeRuneInString(code[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(code) && (code[j] >= '0' && code[j] <= '9' || code[j] == '.') {
+				j++
+			}
+			var num T
+			if n, err := strconv.Atoi(code[i:j]); err == nil {
+				num = n
+			} else if f, err := strconv.ParseFloat(code[i:j], 64); err == nil {
+				num = f
+			} else {
+				return nil, fmt.Errorf("Template: bad number %q", code[i:j])
+			}
+			tokens = append(tokens, templateToken{kind: templateNum, text: code[i:j], num: num})
+			i = j
+		case templateIdentStart(c):
+			j := i + size
+			for j < len(code) {
+				r, n := utf8.DecodeRuneInString(code[j:])
+				if !templateIdentStart(r) && !unicode.IsDigit(r) {
+					break
+				}
+				j += n
+			}
+			tokens = append(tokens, templateToken{kind: templateIdent, text: code[i:j]})
+			i = j
+		case c == '\'' || c == '"':
+			var buf bytes.Buffer
+			j := i + 1
+			for j < len(code) {
+				r, n := utf8.DecodeRuneInString(code[j:])
+				if r == c {
+					break
+				}
+				j += n
+				if r == '\\' && j < len(code) {
+					r, n = utf8.DecodeRuneInString(code[j:])
+					j += n
+					switch r {
+					case 'n':
+						r = '\n'
+					case 't':
+						r = '\t'
+					}
+				}
+				buf.WriteRune(r)
+			}
+			if j >= len(code) {
+				return nil, fmt.Errorf("Template: unterminated string in %q", code)
+			}
+			tokens = append(tokens, templateToken{kind: templateStr, text: buf.String()})
+			i = j + 1
+		default:
+			found := false
+			for _, punct := range templatePuncts {
+				if strings.HasPrefix(code[i:], punct) {
+					tokens = append(tokens, templateToken{kind: templatePunct, text: punct})
+					i += len(punct)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("Template: unexpected %q in %q", c, code)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// Internal function for the runes an identifier can start with, like JavaScript's
+func templateIdentStart(c rune) bool {
+	return c == '_' || c == '$' || unicode.IsLetter(c)
+}
+
+// A parsed template statement or expression
+type templateNode interface{}
+
+type (
+	templateTextNode   struct{ text string }
+	templateOutputNode struct {
+		x      templateNode
+		escape bool
+	}
+	templateExprNode struct{ x templateNode }
+	templateVarNode  struct {
+		name string
+		x    templateNode
+	}
+	templateIfNode struct {
+		cond      templateNode
+		then, els []templateNode
+	}
+	templateForInNode struct {
+		name string
+		x    templateNode
+		body []templateNode
+	}
+	templateForNode struct {
+		init, cond, update templateNode
+		body               []templateNode
+	}
+	templateReturnNode  struct{ x templateNode }
+	templateLiteralNode struct{ value T }
+	templateIdentNode   struct{ name string }
+	templateMemberNode  struct{ obj, key templateNode }
+	templateCallNode    struct {
+		fn   templateNode
+		args []templateNode
+	}
+	templateUnaryNode struct {
+		op string
+		x  templateNode
+	}
+	templateBinaryNode struct {
+		op   string
+		l, r templateNode
+	}
+	templateCondNode  struct{ cond, a, b templateNode }
+	templateArrayNode struct{ elems []templateNode }
+	templateFuncNode  struct {
+		params []string
+		body   []templateNode
+	}
+	templateAssignNode struct {
+		name string
+		op   string
+		x    templateNode
+	}
+)
+
+// A recursive descent parser for template tokens
+type templateParser struct {
+	tokens []templateToken
+	pos    int
+}
+
+func (p *templateParser) peek() templateToken {
+	return p.peekAt(0)
+}
+
+// Look ahead n tokens, without consuming any
+func (p *templateParser) peekAt(n int) templateToken {
+	if p.pos+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+n]
+}
+
+func (p *templateParser) next() templateToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != templateEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *templateParser) fail(format string, args ...interface{}) {
+	panic(fmt.Sprintf(format, args...))
+}
+
+// Is the next token of kind, and if text isn't empty, that text?
+func (p *templateParser) at(kind int, text string) bool {
+	tok := p.peek()
+	return tok.kind == kind && (text == "" || tok.text == text)
+}
+
+// Consume the next token if it's the punctuation or keyword given
+func (p *templateParser) accept(text string) bool {
+	if p.at(templatePunct, text) || p.at(templateIdent, text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *templateParser) expect(text string) {
+	if !p.accept(text) {
+		p.fail("expected %q, got %q", text, p.peek().text)
+	}
+}
+
+// Parse statements until a closing } or the end of the template
+func (p *templateParser) statements() []templateNode {
+	body := make([]templateNode, 0)
+	for !p.at(templateEOF, "") && !p.at(templatePunct, "}") {
+		if stmt := p.statement(); stmt != nil {
+			body = append(body, stmt)
+		}
+	}
+	return body
+}
+
+// Parse a { … } block, or a single statement
+func (p *templateParser) block() []templateNode {
+	if p.accept("{") {
+		body := p.statements()
+		p.expect("}")
+		return body
+	}
+	return []templateNode{p.statement()}
+}
+
+func (p *templateParser) statement() templateNode {
+	tok := p.peek()
+	switch tok.kind {
+	case templateText:
+		p.next()
+		return &templateTextNode{tok.text}
+	case templateInterpolate, templateEscape:
+		p.next()
+		return &templateOutputNode{parseTemplateExpr(tok.text), tok.kind == templateEscape}
+	}
+	if p.accept(";") {
+		return nil
+	}
+	if p.accept("var") {
+		stmt := p.varStatement()
+		p.accept(";")
+		return stmt
+	}
+	if p.accept("if") {
+		p.expect("(")
+		stmt := &templateIfNode{cond: p.expr()}
+		p.expect(")")
+		stmt.then = p.block()
+		if p.accept("else") {
+			stmt.els = p.block()
+		}
+		return stmt
+	}
+	if p.accept("for") {
+		p.expect("(")
+		if p.peekAt(1).text == "in" || (p.at(templateIdent, "var") && p.peekAt(2).text == "in") {
+			p.accept("var")
+			stmt := &templateForInNode{name: p.next().text}
+			p.expect("in")
+			stmt.x = p.expr()
+			p.expect(")")
+			stmt.body = p.block()
+			return stmt
+		}
+		stmt := &templateForNode{}
+		if p.accept("var") {
+			stmt.init = p.varStatement()
+		} else if !p.at(templatePunct, ";") {
+			stmt.init = &templateExprNode{p.expr()}
+		}
+		p.expect(";")
+		if !p.at(templatePunct, ";") {
+			stmt.cond = p.expr()
+		}
+		p.expect(";")
+		if !p.at(templatePunct, ")") {
+			stmt.update = &templateExprNode{p.expr()}
+		}
+		p.expect(")")
+		stmt.body = p.block()
+		return stmt
+	}
+	if p.accept("return") {
+		stmt := &templateReturnNode{}
+		if !p.at(templatePunct, ";") && !p.at(templatePunct, "}") {
+			stmt.x = p.expr()
+		}
+		p.accept(";")
+		return stmt
+	}
+	stmt := &templateExprNode{p.expr()}
+	p.accept(";")
+	return stmt
+}
+
+func (p *templateParser) varStatement() templateNode {
+	name := p.next()
+	if name.kind != templateIdent {
+		p.fail("expected a variable name, got %q", name.text)
+	}
+	stmt := &templateVarNode{name: name.text}
+	if p.accept("=") {
+		stmt.x = p.expr()
+	}
+	return stmt
+}
+
+// Parse an expression, lowest precedence first
+func (p *templateParser) expr() templateNode {
+	if p.at(templateIdent, "") && p.peekAt(1).kind == templatePunct {
+		switch op := p.peekAt(1).text; op {
+		case "=", "+=", "-=":
+			name := p.next().text
+			p.next()
+			return &templateAssignNode{name, op, p.expr()}
+		case "++", "--":
+			name := p.next().text
+			p.next()
+			return &templateAssignNode{name, op, nil}
+		}
+	}
+	return p.ternary()
+}
+
+func (p *templateParser) ternary() templateNode {
+	cond := p.binary(0)
+	if p.accept("?") {
+		a := p.expr()
+		p.expect(":")
+		return &templateCondNode{cond, a, p.expr()}
+	}
+	return cond
+}
+
+// Binary operators, by increasing precedence
+var templateBinaryOps = [][]string{
+	{"||"},
+	{"&&"},
+	{"===", "!==", "==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *templateParser) binary(level int) templateNode {
+	if level == len(templateBinaryOps) {
+		return p.unary()
+	}
+	l := p.binary(level + 1)
+	for {
+		matched := false
+		for _, op := range templateBinaryOps[level] {
+			if p.at(templatePunct, op) {
+				p.next()
+				l = &templateBinaryNode{op, l, p.binary(level + 1)}
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return l
+		}
+	}
+}
+
+func (p *templateParser) unary() templateNode {
+	for _, op := range []string{"!", "-", "+"} {
+		if p.accept(op) {
+			return &templateUnaryNode{op, p.unary()}
+		}
+	}
+	return p.postfix(p.primary())
+}
+
+func (p *templateParser) postfix(x templateNode) templateNode {
+	for {
+		if p.accept(".") {
+			name := p.next()
+			if name.kind != templateIdent {
+				p.fail("expected a property name, got %q", name.text)
+			}
+			x = &templateMemberNode{x, &templateLiteralNode{name.text}}
+		} else if p.accept("[") {
+			x = &templateMemberNode{x, p.expr()}
+			p.expect("]")
+		} else if p.accept("(") {
+			x = &templateCallNode{x, p.list(")")}
+		} else {
+			return x
+		}
+	}
+}
+
+// Parse comma separated expressions up to the closing punctuation
+func (p *templateParser) list(closing string) []templateNode {
+	elems := make([]templateNode, 0)
+	for !p.accept(closing) {
+		if len(elems) > 0 {
+			p.expect(",")
+		}
+		elems = append(elems, p.expr())
+	}
+	return elems
+}
+
+func (p *templateParser) primary() templateNode {
+	tok := p.next()
+	switch tok.kind {
+	case templateNum:
+		return &templateLiteralNode{tok.num}
+	case templateStr:
+		return &templateLiteralNode{tok.text}
+	case templateIdent:
+		switch tok.text {
+		case "true":
+			return &templateLiteralNode{true}
+		case "false":
+			return &templateLiteralNode{false}
+		case "null", "undefined":
+			return &templateLiteralNode{nil}
+		case "function":
+			fn := &templateFuncNode{params: make([]string, 0)}
+			if p.at(templateIdent, "") {
+				p.next()
+			}
+			p.expect("(")
+			for !p.accept(")") {
+				if len(fn.params) > 0 {
+					p.expect(",")
+				}
+				fn.params = append(fn.params, p.next().text)
+			}
+			p.expect("{")
+			fn.body = p.statements()
+			p.expect("}")
+			return fn
+		}
+		return &templateIdentNode{tok.text}
+	case templatePunct:
+		if tok.text == "(" {
+			x := p.expr()
+			p.expect(")")
+			return x
+		}
+		if tok.text == "[" {
+			return &templateArrayNode{p.list("]")}
+		}
+	case templateText, templateInterpolate, templateEscape:
+		p.fail("unclosed code block before %q", tok.text)
+	}
+	p.fail("unexpected %q", tok.text)
+	return nil
+}
+
+// Internal function to parse the code of an interpolate or escape block
+func parseTemplateExpr(code string) templateNode {
+	tokens, err := lexTemplateCode(code)
+	if err != nil {
+		panic(err.Error())
+	}
+	p := &templateParser{tokens: append(tokens, templateToken{kind: templateEOF})}
+	x := p.expr()
+	p.accept(";")
+	if !p.at(templateEOF, "") {
+		p.fail("unexpected %q in %q", p.peek().text, code)
+	}
+	return x
+}
+
+// Variables, local to a function or the whole template
+type templateScope struct {
+	vars   map[string]T
+	parent *templateScope
+}
+
+func (this *templateScope) lookup(name string) (*templateScope, bool) {
+	for scope := this; scope != nil; scope = scope.parent {
+		if _, ok := scope.vars[name]; ok {
+			return scope, true
+		}
+	}
+	return nil, false
+}
+
+// How many loop iterations and function calls a render may take, and how deeply
+// template functions may recurse, before it stops with an error
+const (
+	templateMaxSteps = 1000000
+	templateMaxDepth = 1000
+)
+
+// The state of rendering a template
+type templateRender struct {
+	out      bytes.Buffer
+	data     T
+	variable string
+	steps    int
+	depth    int
+}
+
+// Count a loop iteration or function call against the render's budget
+func (this *templateRender) step() {
+	this.steps++
+	if this.steps > templateMaxSteps {
+		panic(fmt.Sprintf("gave up after %d loop iterations and calls", templateMaxSteps))
+	}
+}
+
+// Run statements, returning early with a value for a return statement
+func (this *templateRender) exec(body []templateNode, scope *templateScope) (T, bool) {
+	for _, node := range body {
+		switch stmt := node.(type) {
+		case *templateTextNode:
+			this.out.WriteString(stmt.text)
+		case *templateOutputNode:
+			value := templateString(this.eval(stmt.x, scope))
+			if stmt.escape {
+				value = Escape(value).(string)
+			}
+			this.out.WriteString(value)
+		case *templateExprNode:
+			this.eval(stmt.x, scope)
+		case *templateVarNode:
+			var value T
+			if stmt.x != nil {
+				value = this.eval(stmt.x, scope)
+			}
+			scope.vars[stmt.name] = value
+		case *templateIfNode:
+			branch := stmt.els
+			if templateTruthy(this.eval(stmt.cond, scope)) {
+				branch = stmt.then
+			}
+			if ret, returned := this.exec(branch, scope); returned {
+				return ret, true
+			}
+		case *templateForInNode:
+			for _, key := range templateKeys(this.eval(stmt.x, scope)) {
+				this.step()
+				scope.vars[stmt.name] = key
+				if ret, returned := this.exec(stmt.body, scope); returned {
+					return ret, true
+				}
+			}
+		case *templateForNode:
+			if stmt.init != nil {
+				this.exec([]templateNode{stmt.init}, scope)
+			}
+			for stmt.cond == nil || templateTruthy(this.eval(stmt.cond, scope)) {
+				this.step()
+				if ret, returned := this.exec(stmt.body, scope); returned {
+					return ret, true
+				}
+				if stmt.update != nil {
+					this.exec([]templateNode{stmt.update}, scope)
+				}
+			}
+		case *templateReturnNode:
+			var value T
+			if stmt.x != nil {
+				value = this.eval(stmt.x, scope)
+			}
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// Look up a variable: locals first, then the data, then `_` and print
+func (this *templateRender) lookup(name string, scope *templateScope) T {
+	if s, ok := scope.lookup(name); ok {
+		return s.vars[name]
+	}
+	if this.variable != "" {
+		if name == this.variable {
+			return this.data
+		}
+	} else if value, ok := templateMember(this.data, name); ok {
+		return value
+	}
+	switch name {
+	case "_":
+		return TemplateHelpers
+	case "print":
+		return func(args ...T) T {
+			for _, arg := range args {
+				this.out.WriteString(templateString(arg))
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// Evaluate an expression
+func (this *templateRender) eval(node templateNode, scope *templateScope) T {
+	switch x := node.(type) {
+	case *templateLiteralNode:
+		return x.value
+	case *templateIdentNode:
+		return this.lookup(x.name, scope)
+	case *templateArrayNode:
+		list := make([]T, len(x.elems))
+		for i, elem := range x.elems {
+			list[i] = this.eval(elem, scope)
+		}
+		return list
+	case *templateMemberNode:
+		value, _ := templateMember(this.eval(x.obj, scope), this.eval(x.key, scope))
+		return value
+	case *templateCallNode:
+		fn := this.eval(x.fn, scope)
+		args := make([]T, len(x.args))
+		for i, arg := range x.args {
+			args[i] = this.eval(arg, scope)
+		}
+		return callFunc(fn, args)
+	case *templateFuncNode:
+		return func(args ...T) T {
+			this.step()
+			if this.depth >= templateMaxDepth {
+				panic(fmt.Sprintf("functions nested more than %d deep", templateMaxDepth))
+			}
+			this.depth++
+			defer func() { this.depth-- }()
+			local := &templateScope{vars: map[string]T{}, parent: scope}
+			for i, param := range x.params {
+				if i < len(args) {
+					local.vars[param] = args[i]
+				} else {
+					local.vars[param] = nil
+				}
+			}
+			ret, _ := this.exec(x.body, local)
+			return ret
+		}
+	case *templateUnaryNode:
+		value := this.eval(x.x, scope)
+		if x.op == "!" {
+			return !templateTruthy(value)
+		}
+		f, _ := toFloat64(value)
+		if x.op == "-" {
+			if i, ok := value.(int); ok {
+				return -i
+			}
+			return -f
+		}
+		return f
+	case *templateCondNode:
+		if templateTruthy(this.eval(x.cond, scope)) {
+			return this.eval(x.a, scope)
+		}
+		return this.eval(x.b, scope)
+	case *templateAssignNode:
+		target := scope
+		if s, ok := scope.lookup(x.name); ok {
+			target = s
+		}
+		var value T
+		switch x.op {
+		case "=":
+			value = this.eval(x.x, scope)
+		case "+=":
+			value = templateArithmetic("+", target.vars[x.name], this.eval(x.x, scope))
+		case "-=":
+			value = templateArithmetic("-", target.vars[x.name], this.eval(x.x, scope))
+		case "++":
+			value = templateArithmetic("+", target.vars[x.name], 1)
+		case "--":
+			value = templateArithmetic("-", target.vars[x.name], 1)
+		}
+		target.vars[x.name] = value
+		return value
+	case *templateBinaryNode:
+		l := this.eval(x.l, scope)
+		switch x.op {
+		case "&&":
+			if !templateTruthy(l) {
+				return l
+			}
+			return this.eval(x.r, scope)
+		case "||":
+			if templateTruthy(l) {
+				return l
+			}
+			return this.eval(x.r, scope)
+		}
+		r := this.eval(x.r, scope)
+		switch x.op {
+		case "==", "===":
+			return templateEquals(l, r)
+		case "!=", "!==":
+			return !templateEquals(l, r)
+		case "<", "<=", ">", ">=":
+			return templateCompare(x.op, l, r)
+		}
+		return templateArithmetic(x.op, l, r)
+	}
+	panic(fmt.Sprintf("can't evaluate %T", node))
+}
+
+// Internal function to make a func(...T) T out of a template function argument
+func templateFunc(fn T) func(...T) T {
+	return func(args ...T) T { return callFunc(fn, args) }
+}
+
+// Internal function to look up a property: array indexes and length, map keys,
+// and struct fields, with the first letter capitalized if need be, ie user.name -> User.Name
+func templateMember(obj T, key T) (T, bool) {
+	if obj == nil {
+		return nil, false
+	}
+	name := templateString(key)
+	if IsOrderedMap(obj) {
+		if name == "length" && !obj.(*OrderedMap).Has(key) {
+			return obj.(*OrderedMap).Len(), true
+		}
+		return obj.(*OrderedMap).Get(key)
+	}
+	if m, ok := obj.(map[T]T); ok {
+		value, found := m[key]
+		return value, found
+	}
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.String:
+		if name == "length" {
+			return v.Len(), true
+		}
+		if f, ok := toFloat64(key); ok && f >= 0 && int(f) < v.Len() && v.Kind() != reflect.String {
+			return v.Index(int(f)).Interface(), true
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			if value := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())); value.IsValid() {
+				return value.Interface(), true
+			}
+		}
+		if name == "length" {
+			return v.Len(), true
+		}
+	case reflect.Struct:
+		field := v.FieldByName(name)
+		if !field.IsValid() && name != "" {
+			field = v.FieldByName(strings.ToUpper(name[:1]) + name[1:])
+		}
+		if field.IsValid() && field.CanInterface() {
+			return field.Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// Internal function listing the keys (or indexes) a for-in loop visits
+func templateKeys(obj T) []T {
+	keys := make([]T, 0)
+	if obj == nil {
+		return keys
+	}
+	if IsMap(obj) {
+		return sortedKeys(obj)
+	}
+	if IsOrderedMap(obj) {
+		return Keys(obj)
+	}
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			keys = append(keys, i)
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.Interface())
+		}
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				keys = append(keys, v.Type().Field(i).Name)
+			}
+		}
+	}
+	return keys
+}
+
+// Internal function for JavaScript-like truthiness: nil, false, 0, NaN and "" are false
+func templateTruthy(value T) bool {
+	if value == nil {
+		return false
+	}
+	if b, ok := value.(bool); ok {
+		return b
+	}
+	if s, ok := value.(string); ok {
+		return s != ""
+	}
+	if f, ok := toFloat64(value); ok {
+		return f != 0 && !math.IsNaN(f)
+	}
+	return true
+}
+
+// Internal function to stringify a value for output; nil is the empty string,
+// and whole floats print without decimals, as JavaScript would
+func templateString(value T) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []T:
+		parts := make([]string, len(v))
+		for i, elem := range v {
+			parts[i] = templateString(elem)
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// Internal function to compare values, numbers by value whatever their type
+func templateEquals(a T, b T) bool {
+	fa, aok := toFloat64(a)
+	fb, bok := toFloat64(b)
+	if aok && bok {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Internal function for <, <=, > and >=, on numbers or strings
+func templateCompare(op string, a T, b T) bool {
+	var cmp int
+	fa, aok := toFloat64(a)
+	fb, bok := toFloat64(b)
+	if aok && bok {
+		if fa < fb {
+			cmp = -1
+		} else if fa > fb {
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(templateString(a), templateString(b))
+	}
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+// Internal function for + - * / %, where + joins strings, and ints stay ints unless divided unevenly
+func templateArithmetic(op string, a T, b T) T {
+	_, aIsString := a.(string)
+	_, bIsString := b.(string)
+	if op == "+" && (aIsString || bIsString) {
+		return templateString(a) + templateString(b)
+	}
+	ia, aIsInt := a.(int)
+	ib, bIsInt := b.(int)
+	if a == nil {
+		aIsInt = true
+	}
+	if aIsInt && bIsInt {
+		switch op {
+		case "+":
+			return ia + ib
+		case "-":
+			return ia - ib
+		case "*":
+			return ia * ib
+		case "/":
+			if ib != 0 && ia%ib == 0 {
+				return ia / ib
+			}
+		case "%":
+			if ib != 0 {
+				return ia % ib
+			}
+		}
+	}
+	fa, _ := toFloat64(a)
+	fb, _ := toFloat64(b)
+	switch op {
+	case "+":
+		return fa + fb
+	case "-":
+		return fa - fb
+	case "*":
+		return fa * fb
+	case "/":
+		return fa / fb
+	}
+	return math.Mod(fa, fb)
+}
@@ -8,11 +8,21 @@
 package underscore
 
 import (
+	"bytes"
+	"container/list"
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
+	"reflect"
+	"runtime"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 _	"os"
 )
@@ -26,6 +36,16 @@ type Valuer interface {
 }
 
 // An Underscore custom type for OOP-style usage, as opposed to functional use.
+// Methods return a new *Underscore wrapping their result, leaving the receiver as it was,
+// so calls can be strung together and the end result read with Value().
+// Wrappers are immutable: no method changes the receiver or the wrapped value, results that
+// need changes are made on copies, so a chain can be branched, and wrappers shared between
+// goroutines.  Functions passed in (to Each, Tap, Pipe...) shouldn't change the values they're given.
+// Methods don't return errors, so the few that can fail panic, like template.Must: Flow, FlowRight
+// and Compose when the functions don't fit together, Template when the text doesn't compile, Call
+// for a name that wasn't mixed in, Curry and Bind for a missing function or method, the Function
+// Functions (Memoize, Once, Throttle...) when the wrapped value isn't the function type they take,
+// and Patch when an op fails.  Call the package function instead to get the error.
 // OOP-style example: list:= []T{"i",10,5.3}; fmt.Sprint( New(list).Filter(       func(v T,b T,c T) bool { _,ok:=v.(int);return ok}) ) -> [5]
 // vs Functional    : list:= []T{"i",10,5.3}; fmt.Sprint(           Filter( list, func(v T,b T,c T) bool { _,ok:=v.(int);return ok} ) ) -> [5]
 type Underscore struct {
@@ -120,6 +140,8 @@ func Each(elemslist_or_map T, iterator eachlistiterator) {
 				return
 			}
 		}
+	} else if IsOrderedMap(elemslist_or_map) {
+		elemslist_or_map.(*OrderedMap).Each(iterator)
 	} else {
 		fmt.Printf("Each isnt doing anything useful with first arg, %v\n", elemslist_or_map)
 	}
@@ -184,22 +206,26 @@ const ReduceError = "Reduce of empty array with no initial value"
 // Aliased as `FoldL`
 func Reduce(obj []T, iterator func(T, T, T, T) T, memo ...T) (T, string) {
 	initial := len(memo) > 0
+	var result T
+	if initial {
+		result = memo[0]
+	}
 	if obj == nil {
 		obj = make([]T, 0)
 	}
 	Each(obj, func(value T, index T, list T) bool {
 		if !initial {
-			memo[0] = value
+			result = value
 			initial = true
 		} else {
-			memo[0] = iterator(memo[0], value, index, list)
+			result = iterator(result, value, index, list)
 		}
 		return eachContinue
 	})
 	if !initial {
 		return nil, ReduceError
 	}
-	return memo[0], ""
+	return result, ""
 }
 
 // **Inject** builds up a single result from a list of values
@@ -371,7 +397,7 @@ var Include func(obj T, target T, opt_comparator ...func(T, T) bool) bool = Cont
 func Invoke(obj T, method func(this T, thisArgs ...T) T, args ...T) []T {
 	//var isFunc = IsFunction(method);
 	return Map(obj, func(value T, key T, origObj T) T {
-		return method(value, args)
+		return method(value, args...)
 	})
 }
 
@@ -492,16 +518,22 @@ func Shuffle(obj []T) []T {
 }
 
 // Sample **n** random values from a collection.
-// If **n** is not specified, returns a single random element.
+// If **n** is not specified, returns a single random element, or nil if there are none.
 func Sample(obj T, opt_n ...int) T {
 	if IsMap(obj) {
 		vals := Values(obj.(map[T]T))
-		return vals[Random(len(vals))]
+		if len(vals) == 0 {
+			return nil
+		}
+		return vals[Random(len(vals)-1)]
 	}
 	if opt_n == nil || len(opt_n) == 0 {
-		return obj.([]T)[Random(len(obj.([]T)))]
+		if len(obj.([]T)) == 0 {
+			return nil
+		}
+		return obj.([]T)[Random(len(obj.([]T))-1)]
 	}
-	return Shuffle(obj.([]T))[0:MinInt(opt_n[0], len(obj.([]T)))]
+	return Shuffle(obj.([]T))[0:MaxInt(0, MinInt(opt_n[0], len(obj.([]T))))]
 }
 
 // An internal function to generate lookup iterators
@@ -605,9 +637,11 @@ func SortBySorter(obj, value T, orderby func(a, b *map[T]T) bool) []T {
 }
 
 // An internal function used for aggregate "group by" operations.
-func group(behavior func(result map[T]T, k T, v T)) func(o T, v T) map[T]T {
-	return func(obj T, value T) map[T]T {
-		result := make(map[T]T, 0)
+// Results are collected in an *OrderedMap, so keys come out in first-seen order, and
+// returned as one when obj is an *OrderedMap, otherwise as a map[T]T
+func group(behavior func(result *OrderedMap, k T, v T)) func(o T, v T) T {
+	return func(obj T, value T) T {
+		result := new(OrderedMap)
 		var iterator func(T, T, T) T
 		if value == nil {
 			iterator = Identity
@@ -621,49 +655,53 @@ func group(behavior func(result map[T]T, k T, v T)) func(o T, v T) map[T]T {
 			behavior(result, key, value)
 			return eachContinue
 		})
-		return result
+		if IsOrderedMap(obj) {
+			return result
+		}
+		return result.ToMap()
 	}
 }
 
 // Groups the object's values by a criterion. Pass either a string attribute
 // to group by, or a function that returns the criterion.
-var GroupBy = group(func(result map[T]T, key T, value T) {
+// Grouping an *OrderedMap gives an *OrderedMap, with groups in the order their keys were first seen.
+var GroupBy func(obj T, value T) T = group(func(result *OrderedMap, key T, value T) {
 	if key == nil {
 		return
 	}
-	if Has(result, key) {
+	if slice, ok := result.Get(key); ok {
 		//fmt.Printf("in group, got res %v, key %v, val %v\n\n",result,key,value)
-		slice := result[key].([]T)
-		slice = append(slice, value)
-		result[key] = slice
+		result.Set(key, append(slice.([]T), value))
 	} else {
 		slice := make([]T, 1)
 		slice[0] = value
-		result[key] = slice
+		result.Set(key, slice)
 	}
 })
 
 // Indexes the object's values by a criterion, similar to `groupBy`, but for
 // when you know that your index values will be unique.
-var IndexBy = group(func(result map[T]T, key T, value T) {
+// Indexing an *OrderedMap gives an *OrderedMap, with keys in the order they were first seen.
+var IndexBy func(obj T, value T) T = group(func(result *OrderedMap, key T, value T) {
 	if key == nil {
 		return
 	}
-	result[key] = value
+	result.Set(key, value)
 })
 
 // Counts instances of an object that group by a certain criterion. Pass
 // either a string attribute to count by, or a function that returns the
 // criterion.
-var CountBy = group(func(result map[T]T, key T, value T) {
+// Counting an *OrderedMap gives an *OrderedMap, with counts in the order their keys were first seen.
+var CountBy func(obj T, value T) T = group(func(result *OrderedMap, key T, value T) {
 	if key == nil {
 		return
 	}
-	if Has(result, key) {
+	if count, ok := result.Get(key); ok {
 		//fmt.Printf("in group, got res %v, key %v, val %v\n\n",result,key,value)
-		result[key] = (result[key]).(int) + 1
+		result.Set(key, count.(int)+1)
 	} else {
-		result[key] = 1
+		result.Set(key, 1)
 	}
 })
 
@@ -724,8 +762,8 @@ func ToArray(obj T) []T {
 	if IsArray(obj) || IsArrayOfMaps(obj) || IsString(obj) {
 		return Map(obj, Identity)
 	}
-	if IsMap(obj) {
-		return Values(obj.(map[T]T))
+	if IsMap(obj) || IsOrderedMap(obj) {
+		return Values(obj)
 	}
 	fmt.Printf("Error: ToArray, got something I dont know what to do with %v\n", obj)
 	return nil
@@ -745,6 +783,9 @@ func Size(obj T) int {
 	if IsMap(obj) {
 		return len(obj.(map[T]T))
 	}
+	if IsOrderedMap(obj) {
+		return obj.(*OrderedMap).Len()
+	}
 	if IsString(obj) {
 		return len(obj.(string))
 	} else {
@@ -1106,6 +1147,7 @@ func Zip(arrays ...[]T) []T {
 // Converts lists into objects. Pass either a single array of `[key, value]`
 // pairs, or two parallel arrays of the same length -- one of keys, and one of
 // the corresponding values.
+// To keep keys in the order given, use NewOrderedMap(key, value, ...) instead.
 func Object(pairs_or_two_arrays ...[]T) map[T]T {
 	if pairs_or_two_arrays == nil {
 		return nil
@@ -1231,73 +1273,577 @@ func Range(start_stop_and_step ...int) []T {
 
 // Function Functions
 
+// A placeholder for Partial and PartialRight, like Underscore's `_`, leaving
+// that argument open to be filled in when the partial function is called.
+// Partial(subtract, Placeholder, 5)(20) -> subtract(20, 5)
+var Placeholder T = placeholder{}
+
+// The type of Placeholder, unexported so it can't be confused with any other value
+type placeholder struct{}
+
+// Internal function to fill the placeholders in savedArgs from laterArgs,
+// returning the filled in args and the laterArgs left over.  savedArgs is not changed.
+func fillPlaceholders(savedArgs []T, laterArgs []T) ([]T, []T) {
+	args := make([]T, len(savedArgs), len(savedArgs)+len(laterArgs))
+	copy(args, savedArgs)
+	next := 0
+	for i, arg := range args {
+		if arg == Placeholder && next < len(laterArgs) {
+			args[i] = laterArgs[next]
+			next += 1
+		}
+	}
+	return args, laterArgs[next:]
+}
+
 // Partially apply a function by creating a version that has had some of its
 // arguments pre-filled, without changing its dynamic `this` context.
+// Pass Placeholder for arguments to be filled in later, in order.
+// Safe to call repeatedly and from multiple goroutines.
 func Partial(fn func(...T) T, savedArgs ...T) func(...T) T {
+	savedArgs = append([]T{}, savedArgs...)
 	return func(laterArgs ...T) T {
-		//args := make([]T, len(savedArgs) + len(laterArgs))
-		args := append(savedArgs, laterArgs...)
-		//for i,v := range savedArgs {
-		//args[i] = v
-		//}
-		//for j,v := range laterArgs {
-		//args[ len(savedArgs) + j ]= v
-		//}
-		return fn(args...)
+		args, rest := fillPlaceholders(savedArgs, laterArgs)
+		return fn(append(args, rest...)...)
+	}
+}
+
+// Like Partial, but pre-fills the rightmost arguments.  The later arguments come
+// first, except as many as are needed to fill any placeholders in savedArgs.
+// PartialRight(greet, Placeholder, "!")("hi", "moe") -> greet("hi", "moe", "!")
+func PartialRight(fn func(...T) T, savedArgs ...T) func(...T) T {
+	savedArgs = append([]T{}, savedArgs...)
+	holes := 0
+	for _, arg := range savedArgs {
+		if arg == Placeholder {
+			holes += 1
+		}
+	}
+	return func(laterArgs ...T) T {
+		leading := len(laterArgs) - holes
+		if leading < 0 {
+			leading = 0
+		}
+		args, _ := fillPlaceholders(savedArgs, laterArgs[leading:])
+		return fn(append(append([]T{}, laterArgs[:leading]...), args...)...)
+	}
+}
+
+// Curry a function of n arguments: calling it with fewer collects those arguments
+// and returns another func(...T) T waiting for the rest, and once n have been
+// collected fn is called with them, ie CurryN(add3, 3)(1)(2, 3) -> add3(1, 2, 3)
+func CurryN(fn func(...T) T, n int) func(...T) T {
+	return curry(fn, n, []T{})
+}
+
+// Internal implementation of CurryN, with the arguments collected so far
+func curry(fn func(...T) T, n int, collected []T) func(...T) T {
+	return func(args ...T) T {
+		all := append(append(make([]T, 0, len(collected)+len(args)), collected...), args...)
+		if len(all) >= n {
+			return fn(all...)
+		}
+		return curry(fn, n, all)
+	}
+}
+
+// Curry a function of any type with a fixed number of arguments, see CurryN,
+// ie Curry(func(a, b int) int { return a + b })(1)(2) -> 3
+// Variadic functions are curried over their fixed arguments only.
+func Curry(fn T) func(...T) T {
+	v := reflect.ValueOf(fn)
+	if fn == nil || v.Kind() != reflect.Func {
+		panic(fmt.Sprintf("Curry: %v is not a function", fn))
+	}
+	n := v.Type().NumIn()
+	if v.Type().IsVariadic() {
+		n -= 1
+	}
+	return CurryN(func(args ...T) T { return callFunc(fn, args) }, n)
+}
+
+// Bind a method of obj, by name, into a func(...T) T, optionally pre-filling
+// some of its arguments like Partial, ie Bind(&account, "Deposit", 100)()
+// Panics if obj has no such exported method.
+func Bind(obj T, methodName string, savedArgs ...T) func(...T) T {
+	method := reflect.ValueOf(obj).MethodByName(methodName)
+	if !method.IsValid() {
+		panic(fmt.Sprintf("Bind: %T has no method %q", obj, methodName))
+	}
+	bound := method.Interface()
+	return Partial(func(args ...T) T { return callFunc(bound, args) }, savedArgs...)
+}
+
+// Bind a number of obj's methods, by name, see Bind.  Go can't replace methods
+// the way Underscore does, so the bound functions are returned by name instead.
+// With no methodNames, every exported method is bound.
+func BindAll(obj T, methodNames ...string) map[string]func(...T) T {
+	if len(methodNames) == 0 {
+		for i := 0; i < reflect.TypeOf(obj).NumMethod(); i++ {
+			methodNames = append(methodNames, reflect.TypeOf(obj).Method(i).Name)
+		}
+	}
+	bound := make(map[string]func(...T) T, len(methodNames))
+	for _, name := range methodNames {
+		bound[name] = Bind(obj, name)
+	}
+	return bound
+}
+
+// Internal function to call a func(...T) T, or any other func by reflection,
+// converting args to the parameter types.  Returns the first result, if any
+func callFunc(fn T, args []T) T {
+	if f, ok := fn.(func(...T) T); ok {
+		return f(args...)
+	}
+	v := reflect.ValueOf(fn)
+	if fn == nil || v.Kind() != reflect.Func {
+		panic(fmt.Sprintf("%v is not a function", fn))
+	}
+	in, err := funcArgs(v.Type(), args)
+	if err != nil {
+		panic(err.Error())
+	}
+	out := v.Call(in)
+	if len(out) == 0 {
+		return nil
+	}
+	return out[0].Interface()
+}
+
+// Internal function, the type of a function's i'th parameter, or nil if it doesn't take that many
+func paramType(typ reflect.Type, i int) reflect.Type {
+	if typ.IsVariadic() && i >= typ.NumIn()-1 {
+		return typ.In(typ.NumIn() - 1).Elem()
+	} else if i < typ.NumIn() {
+		return typ.In(i)
+	}
+	return nil
+}
+
+// Internal function, is this a Go number type?
+func isNumberKind(typ reflect.Type) bool {
+	return typ.Kind() >= reflect.Int && typ.Kind() <= reflect.Float64
+}
+
+// Internal function to make the arguments for calling a function of type typ, converting
+// numbers to the parameter's number type, and values to a named type of the same kind,
+// ie a string to a type Label string, but never an int to a string
+func funcArgs(typ reflect.Type, args []T) ([]reflect.Value, error) {
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		param := paramType(typ, i)
+		if param == nil {
+			return nil, fmt.Errorf("too many arguments to %v", typ)
+		}
+		if arg == nil {
+			in[i] = reflect.Zero(param)
+		} else if reflect.TypeOf(arg).AssignableTo(param) {
+			in[i] = reflect.ValueOf(arg)
+		} else if isNumberKind(reflect.TypeOf(arg)) && isNumberKind(param) {
+			in[i] = reflect.ValueOf(arg).Convert(param)
+		} else if reflect.TypeOf(arg).Kind() == param.Kind() && reflect.TypeOf(arg).ConvertibleTo(param) {
+			in[i] = reflect.ValueOf(arg).Convert(param)
+		} else {
+			return nil, fmt.Errorf("can't pass %v as %v", arg, param)
+		}
+	}
+	required := typ.NumIn()
+	if typ.IsVariadic() {
+		required -= 1
+	}
+	if len(args) < required {
+		return nil, fmt.Errorf("not enough arguments to %v", typ)
+	}
+	return in, nil
+}
+
+// Internal function to read any Go number as a float64
+func toFloat64(value T) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
 	}
+	return 0, false
 }
 
 // Memoize an expensive function by storing its results.
+// Results are keyed on all of the arguments, unless a hasher is given to make the key.
+// Pointer arguments are keyed by address, not by what they point to.
+// Safe to call from multiple goroutines, see NewMemoizer for size limits, expiry and stats.
+// A memoized function calling itself with the same key deadlocks, see Memoizer.Call
 func Memoize(fn func(...T) T, opt_hasher ...func(...T) T) func(...T) T {
-	memo := map[T]T{}
-	var hasher func(...T) T
+	var options MemoizeOptions
 	if opt_hasher != nil {
-		hasher = opt_hasher[0]
-	} else {
-		hasher = identityHasher
+		options.Hasher = opt_hasher[0]
 	}
-	return func(args ...T) T {
-		key := hasher(args...)
-		if Has(memo, key) {
-			return memo[key]
+	return NewMemoizer(fn, options).Call
+}
+
+// Which cached result a Memoizer drops when it's full
+type EvictionPolicy int
+
+const (
+	// Drop the least recently used result
+	EvictLRU EvictionPolicy = iota
+	// Drop the least frequently used result, the least recently used of those if there's a tie
+	EvictLFU
+)
+
+// Options for NewMemoizer.  The zero value keys on all arguments and keeps every result forever.
+type MemoizeOptions struct {
+	// Makes the cache key from the arguments, the result must be usable as a map key.
+	// Defaults to a key made from all of the arguments, with pointers keyed by address
+	Hasher func(...T) T
+	// The most results to keep, 0 for no limit
+	MaxSize int
+	// Which result to drop when MaxSize is reached
+	Eviction EvictionPolicy
+	// How long a result is kept, 0 for forever
+	TTL time.Duration
+	// Measures TTL, defaults to DefaultClock
+	Clock Clock
+}
+
+// Counts of what a Memoizer has done
+type MemoizeStats struct {
+	// Calls answered from the cache
+	Hits int
+	// Calls that ran the function
+	Misses int
+	// Calls that waited on an identical call already running, rather than running the function again
+	Shared int
+	// Results dropped to stay under MaxSize
+	Evictions int
+	// Results dropped for being older than TTL
+	Expirations int
+	// Results currently cached
+	Size int
+}
+
+// A cached result
+type memoEntry struct {
+	key     T
+	value   T
+	expires time.Time
+	uses    int
+	// For EvictLFU, the entry's frequency bucket, and its place in the bucket
+	freq   *list.Element
+	freqEl *list.Element
+}
+
+// For EvictLFU, the cached results used a number of times, most recently used at the front
+type memoFreq struct {
+	uses    int
+	entries *list.List
+}
+
+// A call that's running, for identical calls to wait on
+type memoCall struct {
+	done     chan struct{}
+	value    T
+	panicked T
+}
+
+// A goroutine-safe memoized function, with optional size limit, expiry and statistics.
+// Identical calls made while the function is already running for those arguments
+// wait for that result rather than running it again.
+type Memoizer struct {
+	fn      func(...T) T
+	options MemoizeOptions
+	mu      sync.Mutex
+	entries map[T]*list.Element
+	// Most recently used at the front
+	order *list.List
+	// For EvictLFU, a memoFreq for each number of uses, fewest first
+	freqs *list.List
+	calls map[T]*memoCall
+	stats MemoizeStats
+}
+
+// Create a Memoizer for fn, ie
+// m := NewMemoizer(lookup, MemoizeOptions{MaxSize: 1000, TTL: time.Minute}); m.Call("key")
+func NewMemoizer(fn func(...T) T, options MemoizeOptions) *Memoizer {
+	this := new(Memoizer)
+	this.fn = fn
+	this.options = options
+	if this.options.Hasher == nil {
+		this.options.Hasher = argsHasher
+	}
+	this.options.Clock = clockOrDefault(this.options.Clock)
+	this.entries = make(map[T]*list.Element)
+	this.order = list.New()
+	this.freqs = list.New()
+	this.calls = make(map[T]*memoCall)
+	return this
+}
+
+// The default Memoizer hasher, keying on all of the arguments and their types.
+// Pointers are keyed by address rather than by what they point to, so equal values
+// behind different pointers are cached apart, and changes behind the same one aren't seen.
+func argsHasher(args ...T) T {
+	return fmt.Sprintf("%#v", args)
+}
+
+// Call the memoized function, returning a cached result if there is one.
+// If the function panics, nothing is cached and the panic is passed on to every waiting caller.
+// A function that calls itself through the Memoizer with the same key waits on its own
+// call, and deadlocks; recursive calls must use different keys.
+func (this *Memoizer) Call(args ...T) T {
+	key := this.options.Hasher(args...)
+	this.mu.Lock()
+	if el, ok := this.entries[key]; ok {
+		entry := el.Value.(*memoEntry)
+		if this.options.TTL == 0 || this.options.Clock.Now().Before(entry.expires) {
+			this.stats.Hits += 1
+			entry.uses += 1
+			this.order.MoveToFront(el)
+			if entry.freq != nil {
+				this.bump(entry)
+			}
+			this.mu.Unlock()
+			return entry.value
+		}
+		this.remove(el)
+		this.stats.Expirations += 1
+	}
+	if call, ok := this.calls[key]; ok {
+		this.stats.Shared += 1
+		this.mu.Unlock()
+		<-call.done
+		if call.panicked != nil {
+			panic(call.panicked)
+		}
+		return call.value
+	}
+	call := &memoCall{done: make(chan struct{})}
+	this.calls[key] = call
+	this.stats.Misses += 1
+	this.mu.Unlock()
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				call.panicked = r
+			}
+		}()
+		call.value = this.fn(args...)
+	}()
+
+	this.mu.Lock()
+	delete(this.calls, key)
+	if call.panicked == nil {
+		this.add(key, call.value)
+	}
+	this.mu.Unlock()
+	close(call.done)
+	if call.panicked != nil {
+		panic(call.panicked)
+	}
+	return call.value
+}
+
+// Internal function to cache a result, evicting another if need be.  Called with the lock held
+func (this *Memoizer) add(key T, value T) {
+	for this.options.MaxSize > 0 && this.order.Len() >= this.options.MaxSize {
+		victim := this.order.Back()
+		if this.options.Eviction == EvictLFU {
+			least := this.freqs.Front().Value.(*memoFreq)
+			victim = this.entries[least.entries.Back().Value.(*memoEntry).key]
+		}
+		this.remove(victim)
+		this.stats.Evictions += 1
+	}
+	entry := &memoEntry{key: key, value: value, uses: 1}
+	if this.options.TTL > 0 {
+		entry.expires = this.options.Clock.Now().Add(this.options.TTL)
+	}
+	this.entries[key] = this.order.PushFront(entry)
+	if this.options.Eviction == EvictLFU {
+		front := this.freqs.Front()
+		if front == nil || front.Value.(*memoFreq).uses != 1 {
+			front = this.freqs.PushFront(&memoFreq{uses: 1, entries: list.New()})
 		}
-		memo[key] = fn(args...)
-		return memo[key]
+		entry.freq = front
+		entry.freqEl = front.Value.(*memoFreq).entries.PushFront(entry)
+	}
+}
+
+// Internal function to move a used entry to the frequency bucket for its new number of uses.
+// Called with the lock held
+func (this *Memoizer) bump(entry *memoEntry) {
+	current := entry.freq
+	next := current.Next()
+	if next == nil || next.Value.(*memoFreq).uses != entry.uses {
+		next = this.freqs.InsertAfter(&memoFreq{uses: entry.uses, entries: list.New()}, current)
+	}
+	this.unfreq(entry)
+	entry.freq = next
+	entry.freqEl = next.Value.(*memoFreq).entries.PushFront(entry)
+}
+
+// Internal function to take an entry out of its frequency bucket, dropping the bucket
+// if it's left empty.  Called with the lock held
+func (this *Memoizer) unfreq(entry *memoEntry) {
+	bucket := entry.freq.Value.(*memoFreq)
+	bucket.entries.Remove(entry.freqEl)
+	if bucket.entries.Len() == 0 {
+		this.freqs.Remove(entry.freq)
+	}
+	entry.freq, entry.freqEl = nil, nil
+}
+
+// Internal function to drop a cached result.  Called with the lock held
+func (this *Memoizer) remove(el *list.Element) {
+	this.order.Remove(el)
+	entry := el.Value.(*memoEntry)
+	delete(this.entries, entry.key)
+	if entry.freq != nil {
+		this.unfreq(entry)
+	}
+}
+
+// Drop the cached result for these arguments, if there is one
+func (this *Memoizer) Forget(args ...T) {
+	key := this.options.Hasher(args...)
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if el, ok := this.entries[key]; ok {
+		this.remove(el)
 	}
 }
 
+// Drop every cached result, the stats are kept
+func (this *Memoizer) Clear() {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.entries = make(map[T]*list.Element)
+	this.order.Init()
+	this.freqs.Init()
+}
+
+// A snapshot of the Memoizer's statistics
+func (this *Memoizer) Stats() MemoizeStats {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	stats := this.stats
+	stats.Size = this.order.Len()
+	return stats
+}
+
 //delay_.delay(function, wait, *arguments) 
 //Much like setTimeout, invokes function after wait milliseconds. If you pass the optional arguments, they will be forwarded on to the function when it is invoked.
 //
 //var log = _.bind(console.log, console);
 //_.delay(log, 1000, 'logged later');
 //=> 'logged later' // Appears after one second.
-func DelayNano(fn func() T, waitNanoseconds int64, savedArgs ...T) {
-	go (func() {
-		timer := time.NewTimer( time.Duration( waitNanoseconds))
-		select {
-			case <-timer.C:
-				timer.Stop()
-				timer = nil
-				fn()
-				break
-		}
-	})()
+//
+// Returns a handle to cancel the call, or wait for its result.  Times are measured by DefaultClock,
+// see WithClock for another clock.
+func DelayNano(fn func(...T) T, waitNanoseconds int64, args ...T) *Delayed {
+	return WithClock(nil).DelayNano(fn, waitNanoseconds, args...)
+}
+func Delay(fn func(...T) T, waitMilliseconds int64, args ...T) *Delayed {
+	return DelayNano(fn, waitMilliseconds * 1000000, args...)
+}
+
+// Delay on this Timing's clock, see func DelayNano
+func (this Timing) DelayNano(fn func(...T) T, waitNanoseconds int64, args ...T) *Delayed {
+	delayed := &Delayed{done: make(chan struct{})}
+	delayed.mu.Lock()
+	defer delayed.mu.Unlock()
+	delayed.timer = clockOrDefault(this.clock).AfterFunc(time.Duration(waitNanoseconds), func() {
+		delayed.run(fn, args)
+	})
+	return delayed
 }
-func Delay(fn func() T, waitMilliseconds int64, savedArgs ...T) {
-	DelayNano(fn, waitMilliseconds * 1000000, savedArgs...)
+
+// Delay on this Timing's clock, see func Delay
+func (this Timing) Delay(fn func(...T) T, waitMilliseconds int64, args ...T) *Delayed {
+	return this.DelayNano(fn, waitMilliseconds*1000000, args...)
 }
 
-func DelayAndWait(fn func() T, waitMilliseconds int64, savedArgs ...T) T {
-	var retval T
-   var wg sync.WaitGroup
-   wg.Add(1)
-   Delay(func() T {
-      retval = fn()
-      wg.Done()
-      return nil }, waitMilliseconds)
-   wg.Wait()
-	return retval
+//defer_.defer(function, *arguments) 
+//Defers invoking the function until the current call stack has cleared, similar to using setTimeout with a delay of 0. Useful for performing expensive computations or HTML rendering in chunks without blocking the UI thread from updating. If you pass the optional arguments, they will be forwarded on to the function when it is invoked.
+//
+// In Go, the function runs in its own goroutine as soon as it can, see Delay.
+func Defer(fn func(...T) T, args ...T) *Delayed {
+	return DelayNano(fn, 0, args...)
+}
+
+// Defer on this Timing's clock, see func Defer
+func (this Timing) Defer(fn func(...T) T, args ...T) *Delayed {
+	return this.DelayNano(fn, 0, args...)
+}
+
+// Delay, and block until the function has been called, returning its result.
+// Deprecated: use Delay(fn, wait, args...).Result()
+func DelayAndWait(fn func(...T) T, waitMilliseconds int64, args ...T) T {
+	return Delay(fn, waitMilliseconds, args...).Result()
+}
+
+// A delayed call, made by Delay or Defer
+type Delayed struct {
+	mu        sync.Mutex
+	timer     Timer
+	started   bool
+	cancelled bool
+	result    T
+	done      chan struct{}
+}
+
+// Internal function, the timer's callback
+func (this *Delayed) run(fn func(...T) T, args []T) {
+	this.mu.Lock()
+	if this.cancelled {
+		this.mu.Unlock()
+		return
+	}
+	this.started = true
+	this.mu.Unlock()
+	defer close(this.done)
+	result := fn(args...)
+	this.mu.Lock()
+	this.result = result
+	this.mu.Unlock()
+}
+
+// Stop the call from being made, returns false if it has already been made, or started
+func (this *Delayed) Cancel() bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.started || this.cancelled {
+		return false
+	}
+	this.cancelled = true
+	this.timer.Stop()
+	close(this.done)
+	return true
+}
+
+// A channel that's closed once the call has been made, or cancelled
+func (this *Delayed) Done() <-chan struct{} {
+	return this.done
+}
+
+// Block until the call has been made, returning its result, or nil if it was cancelled
+func (this *Delayed) Result() T {
+	<-this.done
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.result
+}
+
+// Was the call cancelled?
+func (this *Delayed) Cancelled() bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.cancelled
 }
 
 //debounce_.debounce(function, wait, [immediate]) 
@@ -1307,49 +1853,10 @@ func DelayAndWait(fn func() T, waitMilliseconds int64, savedArgs ...T) T {
 //
 //var lazyLayout = _.debounce(calculateLayout, 300);
 //$(window).resize(lazyLayout);
+//
+// Times are measured by DefaultClock, see WithClock for another clock.
 func DebounceNano(fn func() T, waitNanoseconds int64, optImmediate ...bool) func() T {
-	immediate := len(optImmediate) > 0 && optImmediate[0]
-	var timestamp int64 //, result
-	var later func() T
-	var timer *time.Timer = nil
-	var lastResult T
-	later = func() T {
-		last := Now() - timestamp
-      if last < waitNanoseconds && last > 0 {
-         go func() T {
-            timer = time.NewTimer( time.Duration( waitNanoseconds - last))
-            for{ select {
-               case <-timer.C:
-                  timer.Stop()
-                  lastResult = later()
-						return lastResult
-                  }}}()
-      } else {
-			if ! immediate {
-				lastResult = fn()
-				timer.Stop()
-		  }
-      }
-		return lastResult
-	}
-
-	return func() T {
-		timestamp = Now()
-		callNow := immediate && timer == nil
-		if timer == nil {
-			timer = time.NewTimer( time.Duration( waitNanoseconds))
-			go (func() {
-				for{
-					<-timer.C
-						timer.Stop()
-						lastResult = later()
-						break }})()
-		}
-		if callNow {
-			lastResult = fn()
-		}
-		return lastResult
-	}
+	return WithClock(nil).DebounceNano(fn, waitNanoseconds, optImmediate...)
 }
 
 // Millisecond version, instead of nanosecond version, to be at parity with Underscore.js
@@ -1357,6 +1864,21 @@ func Debounce(fn func() T, waitMilliseconds int64, immediate ...bool) func() T {
 	return DebounceNano(fn, waitMilliseconds * 1000000, immediate...)
 }
 
+// Debounce on this Timing's clock, see func DebounceNano
+func (this Timing) DebounceNano(fn func() T, waitNanoseconds int64, optImmediate ...bool) func() T {
+	immediate := len(optImmediate) > 0 && optImmediate[0]
+	debouncer := NewDebouncer(func(...T) T { return fn() }, time.Duration(waitNanoseconds),
+		DebounceOptions{Leading: immediate, Trailing: !immediate, Clock: this.clock})
+	return func() T {
+		return debouncer.Call()
+	}
+}
+
+// Debounce on this Timing's clock, see func Debounce
+func (this Timing) Debounce(fn func() T, waitMilliseconds int64, immediate ...bool) func() T {
+	return this.DebounceNano(fn, waitMilliseconds*1000000, immediate...)
+}
+
 //throttle_.throttle(function, wait, [options]) 
 //Creates and returns a new, throttled version of the passed function, that, when invoked repeatedly, will only actually call the original function at most once per every wait milliseconds. Useful for rate-limiting events that occur faster than you can keep up with.
 //
@@ -1365,13 +1887,19 @@ func Debounce(fn func() T, waitMilliseconds int64, immediate ...bool) func() T {
 //
 //throttled := Throttle(updatePosition, 100)
 //$(window).scroll(throttled);
+//
+// Times are measured by DefaultClock, see WithClock for another clock.
 func ThrottleNano (fn func(...T) T, waitN int64 , options ...map[string]bool) func(...T) T {
-	var result T
-	var timeout *time.Timer
-	var previous int64
-	var leading bool = true
-	var trailing bool = true
-	var timeoutCancelled = false
+	return WithClock(nil).ThrottleNano(fn, waitN, options...)
+}
+
+func Throttle(fn func(...T) T, waitMilliseconds int64, options ...map[string]bool) func(...T) T {
+	return ThrottleNano(fn, waitMilliseconds * 1000000, options...)
+}
+
+// Throttle on this Timing's clock, see func ThrottleNano
+func (this Timing) ThrottleNano(fn func(...T) T, waitN int64, options ...map[string]bool) func(...T) T {
+	leading, trailing := true, true
 	if len(options) > 0 {
 		if v,ok := options[0]["leading"] ; ok {
 			leading = v
@@ -1380,200 +1908,952 @@ func ThrottleNano (fn func(...T) T, waitN int64 , options ...map[string]bool) fu
 			trailing = v
 		}
 	}
-	var later func(...T) T = func(args ...T) T {
-		if leading {
-			previous = Now()
-		} else {
-			previous = 0
+	if !leading && !trailing {
+		// nothing would ever be called
+		return func(...T) T { return nil }
+	}
+	return NewThrottler(fn, time.Duration(waitN), DebounceOptions{Leading: leading, Trailing: trailing, Clock: this.clock}).Call
+}
+
+// Throttle on this Timing's clock, see func Throttle
+func (this Timing) Throttle(fn func(...T) T, waitMilliseconds int64, options ...map[string]bool) func(...T) T {
+	return this.ThrottleNano(fn, waitMilliseconds*1000000, options...)
+}
+
+// Options for NewDebouncer and NewThrottler, following lodash's debounce options.
+// If neither Leading nor Trailing is set, a debouncer calls on the trailing edge
+// and a throttler on both edges, as lodash does by default.
+type DebounceOptions struct {
+	// Call the function at the start of the wait
+	Leading bool
+	// Call the function at the end of the wait, with the latest arguments
+	Trailing bool
+	// The longest the function may be put off for, 0 for no limit
+	MaxWait time.Duration
+	// Defaults to DefaultClock
+	Clock Clock
+}
+
+// A goroutine-safe debounced (or throttled) function, see NewDebouncer.
+// The function is never called while the Debouncer's lock is held,
+// so it may call back into the Debouncer.
+type Debouncer struct {
+	fn       func(...T) T
+	wait     time.Duration
+	options  DebounceOptions
+	// Throttlers call at most once per wait, rather than after wait has passed without calls
+	throttle bool
+	mu       sync.Mutex
+	timer    Timer
+	// Bumped whenever the timer is replaced, so a stale timer that fires anyway is ignored
+	generation int
+	lastArgs   []T
+	hasArgs    bool
+	called     bool
+	lastCall   time.Time
+	lastInvoke time.Time
+	result     T
+}
+
+// Create a Debouncer that calls fn once calls to it have stopped for wait,
+// ie d := NewDebouncer(save, 300 * time.Millisecond, DebounceOptions{MaxWait: time.Second}); d.Call(doc)
+func NewDebouncer(fn func(...T) T, wait time.Duration, opt_options ...DebounceOptions) *Debouncer {
+	this := new(Debouncer)
+	this.fn = fn
+	this.wait = wait
+	if len(opt_options) > 0 {
+		this.options = opt_options[0]
+	}
+	if !this.options.Leading && !this.options.Trailing {
+		this.options.Trailing = true
+	}
+	if this.options.MaxWait > 0 && this.options.MaxWait < wait {
+		this.options.MaxWait = wait
+	}
+	this.options.Clock = clockOrDefault(this.options.Clock)
+	return this
+}
+
+// Create a Debouncer that calls fn at most once per wait,
+// ie t := NewThrottler(updatePosition, 100 * time.Millisecond); t.Call(x, y)
+func NewThrottler(fn func(...T) T, wait time.Duration, opt_options ...DebounceOptions) *Debouncer {
+	options := DebounceOptions{Leading: true, Trailing: true}
+	if len(opt_options) > 0 {
+		options = opt_options[0]
+		if !options.Leading && !options.Trailing {
+			options.Leading, options.Trailing = true, true
 		}
-		if timeout != nil {
-			if timeout.Stop() {
-				timeoutCancelled = true
+	}
+	options.MaxWait = wait
+	this := NewDebouncer(fn, wait, options)
+	this.throttle = true
+	return this
+}
+
+// Call the debounced function, which returns the result of the latest call to fn
+func (this *Debouncer) Call(args ...T) T {
+	this.mu.Lock()
+	now := this.options.Clock.Now()
+	invoking := this.shouldInvoke(now)
+	this.lastArgs, this.hasArgs = args, true
+	this.lastCall, this.called = now, true
+	run := false
+	if invoking {
+		if this.timer == nil {
+			// leading edge
+			this.lastInvoke = now
+			this.startTimer(this.wait)
+			if this.options.Leading {
+				args, run = this.takeArgs(now), true
 			}
-			//timeout = nil, TODO, setting to nil throws panic
+		} else if this.options.MaxWait > 0 {
+			this.startTimer(this.wait)
+			args, run = this.takeArgs(now), true
 		}
-		result = fn(args...)
-		return result
 	}
-	return func(args ...T) T {
-		now := Now()
-		if previous == 0 && ! leading {
-			previous = now
-		}
-		remaining := waitN - (now - previous)
-		if remaining <= 0 || remaining > waitN {
-			if timeout != nil {
-				if timeout.Stop() {
-					timeoutCancelled = true
-				}
-				timeout = nil //see above TODO, but this doesnt cause panic
-			}
-			previous = now
-			result = fn(args...)
-		} else if trailing && timeout == nil {//|| ! timeout.Stop()) {
-			timeout = time.NewTimer(time.Duration(remaining))
-			go (func(){
-				for { select {
-					case <-timeout.C:
-						if timeoutCancelled {
-							timeoutCancelled = false
-						} else {
-							result = later(args...)
-						}
-						break }}})()
-		} else {
-			return result
+	if this.timer == nil {
+		this.startTimer(this.remainingWait(now))
+	}
+	result := this.result
+	this.mu.Unlock()
+	if run {
+		return this.invoke(args)
+	}
+	return result
+}
+
+// Drop any pending trailing call
+func (this *Debouncer) Cancel() {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.stopTimer()
+	this.lastArgs, this.hasArgs = nil, false
+	this.called = false
+	this.lastInvoke = time.Time{}
+}
+
+// Make any pending trailing call now, returns the result of the latest call to fn
+func (this *Debouncer) Flush() T {
+	this.mu.Lock()
+	if this.timer == nil {
+		defer this.mu.Unlock()
+		return this.result
+	}
+	args, run := this.trailingEdge(this.options.Clock.Now())
+	result := this.result
+	this.mu.Unlock()
+	if run {
+		return this.invoke(args)
+	}
+	return result
+}
+
+// Is a call waiting on the timer?
+func (this *Debouncer) Pending() bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.timer != nil
+}
+
+// Internal function, has it been long enough since the last call (or invocation) to call fn?
+func (this *Debouncer) shouldInvoke(now time.Time) bool {
+	if !this.called {
+		return true
+	}
+	if this.throttle {
+		if !this.options.Leading && this.timer == nil {
+			// without a leading call, each burst of calls starts a fresh wait
+			return true
 		}
-		return result
+		sinceInvoke := now.Sub(this.lastInvoke)
+		return sinceInvoke >= this.wait || sinceInvoke < 0
 	}
+	sinceCall := now.Sub(this.lastCall)
+	return sinceCall >= this.wait || sinceCall < 0 ||
+		(this.options.MaxWait > 0 && now.Sub(this.lastInvoke) >= this.options.MaxWait)
 }
 
-func Throttle(fn func(...T) T, waitMilliseconds int64, options ...map[string]bool) func(...T) T {
-	return ThrottleNano(fn, waitMilliseconds * 1000000, options...)
+// Internal function, how long until fn might next be called
+func (this *Debouncer) remainingWait(now time.Time) time.Duration {
+	remaining := this.wait - now.Sub(this.lastCall)
+	if this.options.MaxWait > 0 {
+		if untilMax := this.options.MaxWait - now.Sub(this.lastInvoke); untilMax < remaining {
+			remaining = untilMax
+		}
+	}
+	return remaining
+}
+
+// Internal function to (re)start the timer.  Called with the lock held
+func (this *Debouncer) startTimer(d time.Duration) {
+	this.stopTimer()
+	generation := this.generation
+	this.timer = this.options.Clock.AfterFunc(d, func() {
+		this.timerExpired(generation)
+	})
+}
+
+// Internal function to stop the timer.  Called with the lock held
+func (this *Debouncer) stopTimer() {
+	if this.timer != nil {
+		this.timer.Stop()
+		this.timer = nil
+	}
+	this.generation += 1
+}
+
+func (this *Debouncer) timerExpired(generation int) {
+	this.mu.Lock()
+	if generation != this.generation {
+		this.mu.Unlock()
+		return
+	}
+	now := this.options.Clock.Now()
+	if !this.shouldInvoke(now) {
+		this.startTimer(this.remainingWait(now))
+		this.mu.Unlock()
+		return
+	}
+	args, run := this.trailingEdge(now)
+	this.mu.Unlock()
+	if run {
+		this.invoke(args)
+	}
+}
+
+// Internal function, returns the arguments for a trailing call if one is due.  Called with the lock held
+func (this *Debouncer) trailingEdge(now time.Time) ([]T, bool) {
+	this.stopTimer()
+	if this.options.Trailing && this.hasArgs {
+		return this.takeArgs(now), true
+	}
+	this.lastArgs, this.hasArgs = nil, false
+	return nil, false
+}
+
+// Internal function to claim the latest arguments for a call to fn.  Called with the lock held
+func (this *Debouncer) takeArgs(now time.Time) []T {
+	args := this.lastArgs
+	this.lastArgs, this.hasArgs = nil, false
+	this.lastInvoke = now
+	return args
+}
+
+// Internal function to call fn, without the lock held, and save its result
+func (this *Debouncer) invoke(args []T) T {
+	result := this.fn(args...)
+	this.mu.Lock()
+	this.result = result
+	this.mu.Unlock()
+	return result
+}
+
+// Internal guard for Once and OnceWithError: one call of fn runs at a time while other
+// goroutines wait for it, and the goroutine running fn can tell when fn calls itself
+type onceGuard struct {
+	mu      sync.Mutex // held while fn runs
+	running int64      // the id of the goroutine holding mu, 0 when none
+}
+
+// Lock the guard, unless the current goroutine already holds it, when it returns false
+func (this *onceGuard) enter() bool {
+	id := goroutineId()
+	if atomic.LoadInt64(&this.running) == id {
+		return false
+	}
+	this.mu.Lock()
+	atomic.StoreInt64(&this.running, id)
+	return true
+}
+
+func (this *onceGuard) leave() {
+	atomic.StoreInt64(&this.running, 0)
+	this.mu.Unlock()
+}
+
+// Internal function for the current goroutine's id, from its stack trace's "goroutine 18 [running]:" header
+func goroutineId() int64 {
+	buf := make([]byte, 64)
+	buf = bytes.TrimPrefix(buf[:runtime.Stack(buf, false)], []byte("goroutine "))
+	id, _ := strconv.ParseInt(string(buf[:bytes.IndexByte(buf, ' ')]), 10, 64)
+	return id
 }
 
 // Returns a function that will be executed at most one time, no matter how
 // often you call it. Useful for lazy initialization.
+// Safe to call from multiple goroutines, like sync.Once: calls made while fn is running
+// wait for it to finish, then get its result.  A recursive call from fn itself
+// returns nil rather than deadlocking, as in underscore.js.
+// If fn panics, every later call re-panics with the same value.
 func Once(fn func(...T) T) func(...T) T {
+	var guard onceGuard
 	ran := false
+	panicked := false
 	var memo T
 	return func(args ...T) T {
+		if !guard.enter() {
+			return nil
+		}
+		defer guard.leave()
 		if ran {
+			if panicked {
+				panic(memo)
+			}
 			return memo
 		}
 		ran = true
+		finished := false
+		defer func() {
+			if !finished {
+				memo, panicked, fn = recover(), true, nil
+				panic(memo)
+			}
+		}()
 		memo = fn(args...)
-		fn = nil
+		finished, fn = true, nil
 		return memo
 	}
 }
 
+// Returns a function that will be executed until it first succeeds, returning that result
+// from then on.  Errors (and panics) aren't remembered, so the next call tries again,
+// ie for lazy initialization that can fail: connect := OnceWithError(dial)
+// Safe to call from multiple goroutines, like Once: calls made while fn is running wait
+// for it to finish, then get its result or make the next attempt.  A recursive call from
+// fn itself returns an error rather than deadlocking.
+func OnceWithError(fn func(...T) (T, error)) func(...T) (T, error) {
+	var guard onceGuard
+	done := false
+	var memo T
+	return func(args ...T) (T, error) {
+		if !guard.enter() {
+			return nil, fmt.Errorf("OnceWithError: called recursively")
+		}
+		defer guard.leave()
+		if done {
+			return memo, nil
+		}
+		result, err := fn(args...)
+		if err != nil {
+			return result, err
+		}
+		memo, done = result, true
+		return memo, nil
+	}
+}
+
 // Returns a function that will only be executed after being called N times.
+// Safe to call from multiple goroutines, fn is called without a lock held.
 func After(times int, fn func(...T) T) func(...T) T {
+	var mu sync.Mutex
 	return func(args ...T) T {
+		mu.Lock()
 		if times < 0 {
 			times = 0
 		} else {
 			times -= 1
 		}
-		if times < 1 {
+		ready := times < 1
+		mu.Unlock()
+		if ready {
 			return fn(args...)
 		}
 		return nil
 	}
 }
 
-//Returns an int64 timestamp for the current time, using the fastest method available in the runtime. Useful for implementing timing/animation functions.
-func Now() int64 {
-	return time.Now().UnixNano()
-}
-func NowNano() int64 {
-	return time.Now().UnixNano()
+// Returns a function that will only be executed up to (but not including) the Nth call,
+// the result of the last call being returned after that.  See func Limit
+func Before(times int, fn func(...T) T) func(...T) T {
+	return Limit(fn, times-1)
 }
 
-// Returns the first function passed as an argument to the second,
-// allowing you to adjust arguments, run code before and after, and
-// conditionally execute the original function.
-func Wrap(fn func(...T) T, wrapper func(...T) T) func(...T) T {
+// Returns a function that will be executed at most n times, the result of the last
+// (finished) call being returned after that.  Safe to call from multiple goroutines,
+// fn is called without a lock held, so it may call the returned function.
+func Limit(fn func(...T) T, n int) func(...T) T {
+	var mu sync.Mutex
+	calls := 0
+	var memo T
 	return func(args ...T) T {
-		wrappedargs := make([]T, 0)
-		wrappedargs = append(wrappedargs, fn)
-		wrappedargs = append(wrappedargs, args...)
-		return wrapper(wrappedargs...)
+		mu.Lock()
+		if calls >= n {
+			defer mu.Unlock()
+			return memo
+		}
+		calls += 1
+		mu.Unlock()
+		result := fn(args...)
+		mu.Lock()
+		memo = result
+		mu.Unlock()
+		return result
 	}
 }
 
-// Returns a function that is the composition of a list of functions, each
-// consuming the return value of the function that follows.
-func Compose(funcs ...T) func(...T) T {
-	return func(args ...T) T {
-		for i := len(funcs) - 1; i >= 0; i -= 1 {
-			fn := funcs[i].(func(...T) T)
-			retval := fn(args...)
-			args = nil
-			if retval == nil {
-				continue
-			} else if _, ok := retval.([]T); ok {
-				args = retval.([]T)
-			} else {
-				args = []T{retval}
+// A token bucket limiting how often a function is called, see NewRateLimiter
+type RateLimiter struct {
+	fn     func(...T) T
+	rate   float64
+	burst  int
+	clock  Clock
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// Create a RateLimiter allowing ratePerSecond calls to fn on average, and up to burst at once.
+// A rate of 0 or less means no limit.  Times are measured by DefaultClock, see WithClock for another clock.
+// ie limited := NewRateLimiter(send, 10, 5); limited.Call(msg); if _, ok := limited.TryCall(msg); !ok {...}
+func NewRateLimiter(fn func(...T) T, ratePerSecond float64, burst int) *RateLimiter {
+	return WithClock(nil).NewRateLimiter(fn, ratePerSecond, burst)
+}
+
+// NewRateLimiter on this Timing's clock, see func NewRateLimiter
+func (timing Timing) NewRateLimiter(fn func(...T) T, ratePerSecond float64, burst int) *RateLimiter {
+	this := new(RateLimiter)
+	this.fn = fn
+	this.rate = ratePerSecond
+	if burst < 1 {
+		burst = 1
+	}
+	this.burst = burst
+	this.clock = clockOrDefault(timing.clock)
+	this.tokens = float64(burst)
+	this.last = this.clock.Now()
+	return this
+}
+
+// Returns a function that calls fn at most ratePerSecond times a second on average,
+// up to burst at once, blocking until it's allowed.  See NewRateLimiter for a non-blocking TryCall
+func RateLimit(fn func(...T) T, ratePerSecond float64, burst int) func(...T) T {
+	return NewRateLimiter(fn, ratePerSecond, burst).Call
+}
+
+// Call fn, waiting for the rate limit to allow it
+func (this *RateLimiter) Call(args ...T) T {
+	result, _ := this.CallContext(context.Background(), args...)
+	return result
+}
+
+// Call fn, waiting for the rate limit to allow it unless ctx is done first, in which case ctx's error is returned
+func (this *RateLimiter) CallContext(ctx context.Context, args ...T) (T, error) {
+	for {
+		wait := this.take()
+		if wait == 0 {
+			return this.fn(args...), nil
+		}
+		timer := this.clock.NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Call fn if the rate limit allows it right now, otherwise return false without calling it
+func (this *RateLimiter) TryCall(args ...T) (T, bool) {
+	if this.take() != 0 {
+		return nil, false
+	}
+	return this.fn(args...), true
+}
+
+// Internal function to take a token if there is one, returns 0 if it did,
+// otherwise how long until there will be one
+func (this *RateLimiter) take() time.Duration {
+	if this.rate <= 0 {
+		return 0
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	now := this.clock.Now()
+	if elapsed := now.Sub(this.last); elapsed > 0 {
+		this.tokens = math.Min(float64(this.burst), this.tokens+elapsed.Seconds()*this.rate)
+	}
+	this.last = now
+	if this.tokens >= 1 {
+		this.tokens -= 1
+		return 0
+	}
+	wait := time.Duration((1 - this.tokens) / this.rate * float64(time.Second))
+	if wait <= 0 {
+		wait = 1
+	}
+	return wait
+}
+
+// How long Retry waits after the given failed attempt, counting from 1
+type Backoff func(attempt int) time.Duration
+
+// Wait the same time after every attempt
+func FixedBackoff(wait time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		return wait
+	}
+}
+
+// Wait initial after the first attempt, doubling after each one after that, up to max (0 for no limit)
+func ExponentialBackoff(initial time.Duration, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		wait := initial
+		for i := 1; i < attempt; i++ {
+			if wait > math.MaxInt64/2 {
+				wait = math.MaxInt64
+				break
+			}
+			wait *= 2
+		}
+		if max > 0 && wait > max {
+			wait = max
+		}
+		return wait
+	}
+}
+
+// Spread retries out by waiting a random time between 0 and the backoff's wait ("full jitter"),
+// so many clients failing together don't all retry together
+func Jittered(backoff Backoff) Backoff {
+	return func(attempt int) time.Duration {
+		wait := backoff(attempt)
+		if wait <= 0 {
+			return wait
+		}
+		return time.Duration(rand.Int63n(int64(wait) + 1))
+	}
+}
+
+// When and how often Retry tries again.  The zero value makes 3 attempts, back to back.
+type RetryPolicy struct {
+	// The most calls to make, including the first.  Defaults to 3, unless MaxElapsed is set
+	MaxAttempts int
+	// How long to wait between attempts, defaults to not waiting, or 10ms without MaxAttempts
+	// so MaxElapsed is reached without spinning
+	Backoff Backoff
+	// Give up rather than wait past this long since the first attempt, 0 for no limit
+	MaxElapsed time.Duration
+	// Which errors are worth retrying, defaults to all of them
+	Retryable func(err error) bool
+	// Stop waiting, and give up, when this is done
+	Context context.Context
+	// Measures waits and MaxElapsed, defaults to DefaultClock
+	Clock Clock
+}
+
+// The error Retry returns when it runs out of attempts or time, or its Context is done
+type RetryError struct {
+	// The number of calls made
+	Attempts int
+	// The error from the last call
+	Err error
+	// The Context's error, when it was done before the next attempt
+	Context error
+}
+
+func (this *RetryError) Error() string {
+	if this.Context != nil {
+		return fmt.Sprintf("gave up after %d attempts: %v: %v", this.Attempts, this.Context, this.Err)
+	}
+	return fmt.Sprintf("gave up after %d attempts: %v", this.Attempts, this.Err)
+}
+
+// Both the last call's error and the Context's, so errors.Is matches either
+func (this *RetryError) Unwrap() []error {
+	if this.Context != nil {
+		return []error{this.Err, this.Context}
+	}
+	return []error{this.Err}
+}
+
+// How long Retry waits between attempts when only MaxElapsed limits them and no Backoff is given
+const retryMinWait = 10 * time.Millisecond
+
+// Returns a function that calls fn until it succeeds, following the policy, ie
+// fetch := Retry(get, RetryPolicy{MaxAttempts: 5, Backoff: Jittered(ExponentialBackoff(100 * time.Millisecond, 5 * time.Second))})
+// An error the policy says isn't Retryable is returned as is, right away.
+// When attempts or time run out a *RetryError wrapping the last error is returned.
+// When the Context is done after a failed attempt the *RetryError wraps its error too,
+// and when it's done before the first attempt its error is returned as is.
+func Retry(fn func(...T) (T, error), policy RetryPolicy) func(...T) (T, error) {
+	if policy.MaxAttempts <= 0 && policy.MaxElapsed <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.Backoff == nil && policy.MaxAttempts <= 0 {
+		policy.Backoff = FixedBackoff(retryMinWait)
+	} else if policy.Backoff == nil {
+		policy.Backoff = FixedBackoff(0)
+	}
+	if policy.Context == nil {
+		policy.Context = context.Background()
+	}
+	return func(args ...T) (T, error) {
+		clock := clockOrDefault(policy.Clock)
+		start := clock.Now()
+		var result T
+		var lastErr error
+		for attempt := 1; ; attempt++ {
+			if err := policy.Context.Err(); err != nil {
+				if lastErr == nil {
+					return nil, err
+				}
+				return result, &RetryError{Attempts: attempt - 1, Err: lastErr, Context: err}
+			}
+			var err error
+			result, err = fn(args...)
+			if err == nil {
+				return result, nil
+			}
+			if policy.Retryable != nil && !policy.Retryable(err) {
+				return result, err
+			}
+			lastErr = err
+			if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+				return result, &RetryError{Attempts: attempt, Err: err}
+			}
+			wait := policy.Backoff(attempt)
+			if policy.MaxElapsed > 0 && clock.Now().Add(wait).Sub(start) > policy.MaxElapsed {
+				return result, &RetryError{Attempts: attempt, Err: err}
+			}
+			if wait <= 0 {
+				continue
+			}
+			timer := clock.NewTimer(wait)
+			select {
+			case <-timer.C():
+			case <-policy.Context.Done():
+				timer.Stop()
+				return result, &RetryError{Attempts: attempt, Err: err, Context: policy.Context.Err()}
+			}
+		}
+	}
+}
+
+//Returns an int64 timestamp for the current time, using the fastest method available in the runtime. Useful for implementing timing/animation functions.
+// Reads DefaultClock, see WithClock for another clock.
+func Now() int64 {
+	return WithClock(nil).Now()
+}
+func NowNano() int64 {
+	return WithClock(nil).NowNano()
+}
+
+// Now on this Timing's clock, see func Now
+func (this Timing) Now() int64 {
+	return this.NowNano()
+}
+
+// NowNano on this Timing's clock, see func NowNano
+func (this Timing) NowNano() int64 {
+	return clockOrDefault(this.clock).Now().UnixNano()
+}
+
+// Returns the first function passed as an argument to the second,
+// allowing you to adjust arguments, run code before and after, and
+// conditionally execute the original function.
+func Wrap(fn func(...T) T, wrapper func(...T) T) func(...T) T {
+	return func(args ...T) T {
+		wrappedargs := make([]T, 0)
+		wrappedargs = append(wrappedargs, fn)
+		wrappedargs = append(wrappedargs, args...)
+		return wrapper(wrappedargs...)
+	}
+}
+
+// Returns a function that is the composition of a list of functions, each
+// consuming the return value of the function that follows.
+// A func(...T) T is passed the elements of a []T result as its arguments, and no arguments for nil.
+// Panics if the functions don't fit together, see FlowRight.
+func Compose(funcs ...T) func(...T) T {
+	stages := make([]T, len(funcs))
+	for i, fn := range funcs {
+		stages[i] = fn
+		if f, ok := fn.(func(...T) T); ok && i < len(funcs)-1 {
+			stages[i] = spreadArgs(f)
+		}
+	}
+	flow, err := FlowRight(stages...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return func(args ...T) T {
+		result, err := flow(args...)
+		if err != nil {
+			panic(err.Error())
+		}
+		return result
+	}
+}
+
+// Internal function for Compose, fn taking a single []T argument as its arguments, and nil as none
+func spreadArgs(fn func(...T) T) func(...T) T {
+	return func(args ...T) T {
+		if len(args) == 1 {
+			if list, ok := args[0].([]T); ok {
+				return fn(list...)
+			} else if args[0] == nil {
+				return fn()
+			}
+		}
+		return fn(args...)
+	}
+}
+
+// Returns a function that calls each of funcs in turn, left to right, passing each one's
+// results as the arguments to the next, ie
+// flow, err := Flow(strings.Fields, func(words []string) int { return len(words) }); n, err := flow("a b c")
+// The functions may be of any type.  One whose last result is an error stops the flow when
+// it returns a non-nil error, which is returned wrapped.  One returning a receive channel
+// is async: the flow waits for a value from the channel, and passes that on.
+// The flow returns the last function's result, a []T if it has several, or the
+// arguments themselves when there are no functions.
+// An error is returned straight away if a function's results can't be passed to the next one.
+func Flow(funcs ...T) (func(...T) (T, error), error) {
+	stages := make([]flowStage, len(funcs))
+	for i, fn := range funcs {
+		stage, err := newFlowStage(i, fn)
+		if err != nil {
+			return nil, err
+		}
+		stages[i] = stage
+	}
+	return newFlow(stages)
+}
+
+// Flow, but right to left, like Compose
+func FlowRight(funcs ...T) (func(...T) (T, error), error) {
+	stages := make([]flowStage, len(funcs))
+	for i, fn := range funcs {
+		stage, err := newFlowStage(i, fn)
+		if err != nil {
+			return nil, err
+		}
+		stages[len(funcs)-1-i] = stage
+	}
+	return newFlow(stages)
+}
+
+// Pass value through each of funcs in turn, left to right, and return the result.  See Flow
+func Pipe(value T, funcs ...T) (T, error) {
+	flow, err := Flow(funcs...)
+	if err != nil {
+		return nil, err
+	}
+	return flow(value)
+}
+
+// A function in a Flow, as found by reflection
+type flowStage struct {
+	// The function's position in the list given to Flow, for error messages
+	pos int
+	fn  reflect.Value
+	// Whether the last result is an error
+	failable bool
+	// Whether the result is a channel to wait on for the value to pass on
+	async bool
+	// The types passed on to the next function
+	out []reflect.Type
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func newFlowStage(pos int, fn T) (flowStage, error) {
+	v := reflect.ValueOf(fn)
+	if fn == nil || v.Kind() != reflect.Func || v.IsNil() {
+		return flowStage{}, fmt.Errorf("Flow: funcs[%d] is %T, not a function", pos, fn)
+	}
+	stage := flowStage{pos: pos, fn: v}
+	for j := 0; j < v.Type().NumOut(); j++ {
+		stage.out = append(stage.out, v.Type().Out(j))
+	}
+	if n := len(stage.out); n > 0 && stage.out[n-1] == errorType {
+		stage.failable = true
+		stage.out = stage.out[:n-1]
+	}
+	if len(stage.out) == 1 && stage.out[0].Kind() == reflect.Chan && stage.out[0].ChanDir()&reflect.RecvDir != 0 {
+		stage.async = true
+		stage.out = []reflect.Type{stage.out[0].Elem()}
+	}
+	return stage, nil
+}
+
+// Internal function, check each stage's results can be passed to the next
+func newFlow(stages []flowStage) (func(...T) (T, error), error) {
+	for i := 1; i < len(stages); i++ {
+		if err := stages[i-1].feeds(stages[i]); err != nil {
+			return nil, err
+		}
+	}
+	return func(args ...T) (T, error) {
+		for _, stage := range stages {
+			results, err := stage.call(args)
+			if err != nil {
+				return nil, err
 			}
+			args = results
+		}
+		switch len(args) {
+		case 0:
+			return nil, nil
+		case 1:
+			return args[0], nil
+		}
+		return args, nil
+	}, nil
+}
+
+// Internal function, an error if this stage's results can't be passed to the next
+func (this flowStage) feeds(next flowStage) error {
+	typ := next.fn.Type()
+	required := typ.NumIn()
+	if typ.IsVariadic() {
+		required -= 1
+	}
+	if len(this.out) < required || (!typ.IsVariadic() && len(this.out) > required) {
+		return fmt.Errorf("Flow: funcs[%d] (%v) passes %d values, funcs[%d] (%v) takes %d",
+			this.pos, this.fn.Type(), len(this.out), next.pos, typ, typ.NumIn())
+	}
+	for j, out := range this.out {
+		param := paramType(typ, j)
+		if !out.AssignableTo(param) && out.Kind() != reflect.Interface && !(isNumberKind(out) && isNumberKind(param)) {
+			return fmt.Errorf("Flow: funcs[%d] (%v) passes a %v, funcs[%d] (%v) takes a %v",
+				this.pos, this.fn.Type(), out, next.pos, typ, param)
+		}
+	}
+	return nil
+}
+
+// Internal function to call the stage, waiting on its channel if it's async
+func (this flowStage) call(args []T) ([]T, error) {
+	in, err := funcArgs(this.fn.Type(), args)
+	if err != nil {
+		return nil, fmt.Errorf("Flow: funcs[%d]: %v", this.pos, err)
+	}
+	out := this.fn.Call(in)
+	if this.failable {
+		if errValue := out[len(out)-1]; !errValue.IsNil() {
+			return nil, fmt.Errorf("Flow: funcs[%d]: %w", this.pos, errValue.Interface().(error))
+		}
+		out = out[:len(out)-1]
+	}
+	if this.async {
+		if out[0].IsNil() {
+			return nil, fmt.Errorf("Flow: funcs[%d] returned a nil channel", this.pos)
+		}
+		value, ok := out[0].Recv()
+		if !ok {
+			return nil, fmt.Errorf("Flow: funcs[%d] closed its channel without sending a value", this.pos)
 		}
-		return args[0]
+		out = []reflect.Value{value}
 	}
+	results := make([]T, len(out))
+	for j, value := range out {
+		results[j] = value.Interface()
+	}
+	return results, nil
 }
 
 
 // Map Functions
 
-// Retrieve the names of a maps keys
-func Keys(obj map[T]T) []T {
+// Internal function to look up a key in a map[T]T or *OrderedMap
+func getKey(obj T, key T) (T, bool) {
+	if IsOrderedMap(obj) {
+		return obj.(*OrderedMap).Get(key)
+	}
+	if m, ok := obj.(map[T]T); ok {
+		v, found := m[key]
+		return v, found
+	}
+	return nil, false
+}
+
+// Retrieve the names of a maps keys.
+// An *OrderedMap's keys come back in insertion order
+func Keys(obj T) []T {
 	retval := make([]T, 0)
-	if obj == nil {
-		return retval
+	if IsOrderedMap(obj) {
+		return obj.(*OrderedMap).Keys()
 	}
-	for key := range obj {
-		retval = append(retval, key)
+	if m, ok := obj.(map[T]T); ok {
+		for key := range m {
+			retval = append(retval, key)
+		}
 	}
 	return retval
 }
 
 // Retrieve the values of a maps keys
-func Values(obj map[T]T) []T {
+func Values(obj T) []T {
 	retval := make([]T, 0)
-	if obj == nil {
-		return retval
-	}
-	keys := Keys(obj)
-	for _, key := range keys {
-		retval = append(retval, obj[key])
+	for _, key := range Keys(obj) {
+		v, _ := getKey(obj, key)
+		retval = append(retval, v)
 	}
 	return retval
 }
 
 // Convert an object into a list of `[key, value]` pairs.
-func Pairs(obj map[T]T) []T {
+func Pairs(obj T) []T {
 	keys := Keys(obj)
 	length := len(keys)
 	pairs := make([]T, length)
 	for i := 0; i < length; i++ {
-		pairs[i] = []T{keys[i], obj[keys[i]]}
+		v, _ := getKey(obj, keys[i])
+		pairs[i] = []T{keys[i], v}
 	}
 	return pairs
 }
 
-func Invert(obj map[T]T) map[T]T {
+// Return a copy of the object with keys and values swapped.
+// Inverting an *OrderedMap gives an *OrderedMap, in the original key order
+func Invert(obj T) T {
+	if IsOrderedMap(obj) {
+		return obj.(*OrderedMap).Invert()
+	}
 	result := map[T]T{}
-	for k, v := range obj {
+	for _, k := range Keys(obj) {
+		v, _ := getKey(obj, k)
 		result[v] = k
 	}
 	return result
 }
 
-func Extend(objToExtend map[T]T, args ...T) map[T]T {
+// Copy all of the properties in the passed-in maps (map[T]T or *OrderedMap) onto objToExtend,
+// a map[T]T or *OrderedMap, which is returned.  An *OrderedMap's new keys are appended in the order they're found
+func Extend(objToExtend T, args ...T) T {
+	if IsOrderedMap(objToExtend) {
+		return objToExtend.(*OrderedMap).Extend(args...)
+	}
+	dst, _ := objToExtend.(map[T]T)
 	Each(args, func(objToCopy, key, list T) bool {
-		if !IsMap(objToCopy) {
+		if !IsMap(objToCopy) && !IsOrderedMap(objToCopy) {
 			return eachContinue
 		}
-		for k, v := range objToCopy.(map[T]T) {
-			objToExtend[k] = v
+		for _, k := range Keys(objToCopy) {
+			dst[k], _ = getKey(objToCopy, k)
 		}
 		return eachContinue
 	})
-	return objToExtend
+	return dst
 }
 
 // Return a copy of the object only containing the whitelisted properties.
-func Pick(obj map[T]T, keysToKeep ...T) map[T]T {
+// Picking from an *OrderedMap gives an *OrderedMap, in the original key order
+func Pick(obj T, keysToKeep ...T) T {
+	if IsOrderedMap(obj) {
+		return obj.(*OrderedMap).Pick(keysToKeep...)
+	}
 	copy := map[T]T{}
 	Each(keysToKeep, func(keyToKeep, index, list T) bool {
 		if _, isList := keyToKeep.([]T); isList {
 			for _, keyToKeep := range keyToKeep.([]T) {
-				if v, ok := obj[keyToKeep]; ok {
+				if v, ok := getKey(obj, keyToKeep); ok {
 					copy[keyToKeep] = v
 				}
 			}
-		} else if v, ok := obj[keyToKeep]; ok {
+		} else if v, ok := getKey(obj, keyToKeep); ok {
 			copy[keyToKeep] = v
 		}
 		return eachContinue
@@ -1582,26 +2862,35 @@ func Pick(obj map[T]T, keysToKeep ...T) map[T]T {
 }
 
 // Return a copy of the object without the blacklisted properties.
-func Omit(obj map[T]T, keysToRemove ...T) map[T]T {
+// Omitting from an *OrderedMap gives an *OrderedMap, in the original key order
+func Omit(obj T, keysToRemove ...T) T {
+	if IsOrderedMap(obj) {
+		return obj.(*OrderedMap).Omit(keysToRemove...)
+	}
 	copy := map[T]T{}
 	keysToRemove = Flatten(keysToRemove, true)
-	for k, v := range obj {
+	for _, k := range Keys(obj) {
 		if !Contains(keysToRemove, k) {
-			copy[k] = v
+			copy[k], _ = getKey(obj, k)
 		}
 	}
 	return copy
 }
 
-// Fill in a given object with default properties.
-func Defaults(obj map[T]T, args ...T) map[T]T {
+// Fill in a given object, a map[T]T or *OrderedMap, with default properties.
+// An *OrderedMap's new keys are appended in the order they're found
+func Defaults(objToFill T, args ...T) T {
+	if IsOrderedMap(objToFill) {
+		return objToFill.(*OrderedMap).Defaults(args...)
+	}
+	obj, _ := objToFill.(map[T]T)
 	Each(args, func(val, idx, list T) bool {
-		if !IsMap(val) {
+		if !IsMap(val) && !IsOrderedMap(val) {
 			return eachContinue
 		}
-		for k, v := range val.(map[T]T) {
+		for _, k := range Keys(val) {
 			if _, ok := obj[k]; !ok {
-				obj[k] = v
+				obj[k], _ = getKey(val, k)
 			}
 		}
 		return eachContinue
@@ -1614,12 +2903,197 @@ func Clone(obj T) T {
 	if IsMap(obj) {
 		return Extend(map[T]T{}, obj.(map[T]T))
 	}
+	if IsOrderedMap(obj) {
+		return obj.(*OrderedMap).Clone()
+	}
 	if IsArray(obj) {
-		return obj.([]T)[:]
+		dst := make([]T, len(obj.([]T)))
+		copy(dst, obj.([]T))
+		return dst
 	}
 	return obj
 }
 
+// A key for remembering what's already been copied by CloneDeep, so cycles are
+// cloned as cycles instead of recursing forever
+type cloneSeenKey struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+// Create a deep copy of an object, recursively copying nested slices, maps,
+// *OrderedMaps, struct values and pointers.  Cyclic and shared references are
+// preserved in the copy.  Unexported struct fields are copied shallowly, as
+// reflection can't set them.
+func CloneDeep(obj T) T {
+	if obj == nil {
+		return nil
+	}
+	return cloneDeep(reflect.ValueOf(obj), map[cloneSeenKey]reflect.Value{}).Interface()
+}
+
+// Internal implementation of a recursive `cloneDeep` function.
+func cloneDeep(v reflect.Value, seen map[cloneSeenKey]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		retval := reflect.New(v.Type()).Elem()
+		retval.Set(cloneDeep(v.Elem(), seen))
+		return retval
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		key := cloneSeenKey{v.Type(), v.Pointer(), 0}
+		if c, ok := seen[key]; ok {
+			return c
+		}
+		if v.Type() == reflect.TypeOf((*OrderedMap)(nil)) {
+			src := v.Interface().(*OrderedMap)
+			dst := new(OrderedMap)
+			seen[key] = reflect.ValueOf(dst)
+			src.Each(func(value, k, list T) bool {
+				if value == nil {
+					dst.Set(k, nil)
+				} else {
+					dst.Set(k, cloneDeep(reflect.ValueOf(value), seen).Interface())
+				}
+				return eachContinue
+			})
+			return seen[key]
+		}
+		retval := reflect.New(v.Type().Elem())
+		seen[key] = retval
+		retval.Elem().Set(cloneDeep(v.Elem(), seen))
+		return retval
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		key := cloneSeenKey{v.Type(), v.Pointer(), 0}
+		if c, ok := seen[key]; ok {
+			return c
+		}
+		retval := reflect.MakeMap(v.Type())
+		seen[key] = retval
+		for _, k := range v.MapKeys() {
+			retval.SetMapIndex(k, cloneDeep(v.MapIndex(k), seen))
+		}
+		return retval
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		key := cloneSeenKey{v.Type(), v.Pointer(), v.Len()}
+		if c, ok := seen[key]; ok {
+			return c
+		}
+		retval := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		seen[key] = retval
+		for i := 0; i < v.Len(); i++ {
+			retval.Index(i).Set(cloneDeep(v.Index(i), seen))
+		}
+		return retval
+
+	case reflect.Array:
+		retval := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			retval.Index(i).Set(cloneDeep(v.Index(i), seen))
+		}
+		return retval
+
+	case reflect.Struct:
+		retval := reflect.New(v.Type()).Elem()
+		retval.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if retval.Field(i).CanSet() {
+				retval.Field(i).Set(cloneDeep(v.Field(i), seen))
+			}
+		}
+		return retval
+	}
+	return v
+}
+
+// How MergeDeepWith combines two arrays found at the same key
+type ArrayMergeStrategy int
+
+const (
+	// The later array replaces the earlier one, the default for MergeDeep
+	MergeArraysReplace ArrayMergeStrategy = iota
+	// The later array's elements are appended to the earlier one's
+	MergeArraysAppend
+	// Like append, but skipping elements already present (compared with reflect.DeepEqual)
+	MergeArraysUnion
+)
+
+// Merge maps (map[T]T or *OrderedMap) into a new map, later properties win.
+// Unlike Extend, none of the passed-in maps are changed.
+func Merge(objs ...T) map[T]T {
+	return Extend(map[T]T{}, objs...).(map[T]T)
+}
+
+// Recursively merge maps (map[T]T or *OrderedMap) into a new map, later properties win,
+// and nested maps found at the same key are merged rather than replaced.
+// Arrays are replaced, see MergeDeepWith for other options.
+// The result is a deep copy, sharing nothing with the passed-in maps,
+// ie for layering configuration: MergeDeep(defaults, fileConfig, flagConfig)
+func MergeDeep(objs ...T) map[T]T {
+	return MergeDeepWith(MergeArraysReplace, objs...)
+}
+
+// Recursively merge maps like MergeDeep, combining arrays at the same key using strategy
+func MergeDeepWith(strategy ArrayMergeStrategy, objs ...T) map[T]T {
+	retval := map[T]T{}
+	for _, obj := range objs {
+		if IsMap(obj) || IsOrderedMap(obj) {
+			retval = mergeDeep(retval, obj, strategy).(map[T]T)
+		}
+	}
+	return retval
+}
+
+// Internal implementation of a recursive `mergeDeep` function.
+// Returns dst merged with src; dst is expected to be a copy it's safe to change
+func mergeDeep(dst T, src T, strategy ArrayMergeStrategy) T {
+	isDstMap := IsMap(dst) || IsOrderedMap(dst)
+	isSrcMap := IsMap(src) || IsOrderedMap(src)
+	if isDstMap && isSrcMap {
+		for _, k := range Keys(src) {
+			srcValue, _ := getKey(src, k)
+			dstValue, _ := getKey(dst, k)
+			merged := mergeDeep(dstValue, srcValue, strategy)
+			if IsOrderedMap(dst) {
+				dst.(*OrderedMap).Set(k, merged)
+			} else {
+				dst.(map[T]T)[k] = merged
+			}
+		}
+		return dst
+	}
+	if IsArray(dst) && IsArray(src) {
+		switch strategy {
+		case MergeArraysAppend:
+			return append(dst.([]T), CloneDeep(src).([]T)...)
+		case MergeArraysUnion:
+			retval := dst.([]T)
+			for _, elem := range src.([]T) {
+				if !Any(retval, func(value, index, list T) bool { return reflect.DeepEqual(value, elem) }) {
+					retval = append(retval, CloneDeep(elem))
+				}
+			}
+			return retval
+		}
+	}
+	return CloneDeep(src)
+}
+
 // Invokes interceptor with the obj, and then returns obj.
 // The primary purpose of this method is to "tap into" a method chain, in
 // order to perform operations on intermediate results within the chain.
@@ -1631,10 +3105,738 @@ func Tap(obj T, fn func(...T) T) T {
 // Shortcut function for checking if an object has a given property directly
 // on itself (in other words, not on a prototype).
 func Has(obj T, key T) bool {
-	_, ok := obj.(map[T]T)[key]
+	_, ok := getKey(obj, key)
 	return ok
 }
 
+// Internal function to split a path into its segments.  A path is either a
+// dotted string, ie "a.b.0.c", or a []T of keys and int indexes, ie []T{"a", "b", 0, "c"}
+func pathSegments(path T) []T {
+	if IsArray(path) {
+		return path.([]T)
+	}
+	if s, ok := path.(string); ok {
+		if s == "" {
+			return []T{}
+		}
+		segs := make([]T, 0)
+		for _, segment := range strings.Split(s, ".") {
+			segs = append(segs, segment)
+		}
+		return segs
+	}
+	return []T{path}
+}
+
+// Internal function to read a path segment as an array index
+func pathIndex(segment T) (int, bool) {
+	if i, ok := segment.(int); ok {
+		return i, i >= 0
+	}
+	if s, ok := segment.(string); ok {
+		i, err := strconv.Atoi(s)
+		return i, err == nil && i >= 0
+	}
+	return 0, false
+}
+
+// Internal function to look up one path segment in a map[T]T, *OrderedMap, []T or struct
+func pathGet(obj T, segment T) (T, bool) {
+	if IsArray(obj) {
+		i, ok := pathIndex(segment)
+		if !ok || i >= len(obj.([]T)) {
+			return nil, false
+		}
+		return obj.([]T)[i], true
+	}
+	if isStruct(obj) {
+		field, ok := structField(obj, segment)
+		if !ok {
+			return nil, false
+		}
+		return field.Interface(), true
+	}
+	return getKey(obj, segment)
+}
+
+// Internal function, is this a struct, or a pointer to one, other than an *OrderedMap?
+func isStruct(obj T) bool {
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	return v.IsValid() && v.Kind() == reflect.Struct && !IsOrderedMap(obj)
+}
+
+// Internal function to find an exported field of a struct, or a pointer to one, by name
+func structField(obj T, segment T) (reflect.Value, bool) {
+	name, ok := segment.(string)
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	if !ok || !v.IsValid() || v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	field, found := v.Type().FieldByName(name)
+	if !found || field.PkgPath != "" || len(field.Index) != 1 {
+		return reflect.Value{}, false
+	}
+	return v.Field(field.Index[0]), true
+}
+
+// Internal function to set a struct field, or zero it when keep is false, for updatePath.
+// A struct value can't be changed in place so is copied, as is a pointed to struct when copyOnWrite is true.
+// Values that can't be assigned to the field, other than numbers that convert, leave obj as it was.
+func setStructField(obj T, segment T, value T, keep bool, copyOnWrite bool) T {
+	v := reflect.ValueOf(obj)
+	target := v
+	if v.Kind() != reflect.Ptr || copyOnWrite {
+		target = reflect.New(reflect.Indirect(v).Type())
+		target.Elem().Set(reflect.Indirect(v))
+	}
+	field, _ := structField(target.Interface(), segment)
+	newValue := reflect.Zero(field.Type())
+	if keep && value != nil {
+		newValue = reflect.ValueOf(value)
+		if isNumberKind(newValue.Type()) && isNumberKind(field.Type()) {
+			newValue = newValue.Convert(field.Type())
+		} else if !newValue.Type().AssignableTo(field.Type()) {
+			return obj
+		}
+	}
+	field.Set(newValue)
+	if v.Kind() != reflect.Ptr {
+		return target.Elem().Interface()
+	}
+	return target.Interface()
+}
+
+// Internal implementation of a recursive `updatePath` function.
+// Replaces the value at segs with fn(oldValue, found), or removes it when fn returns false,
+// creating missing maps and arrays along the way when create is true.
+// When copyOnWrite is true, every container along the path is copied rather than changed.
+// Returns the (possibly new) obj.
+func updatePath(obj T, segs []T, fn func(T, bool) (T, bool), create, copyOnWrite bool) T {
+	segment := segs[0]
+	_, isIndex := pathIndex(segment)
+	if IsArray(obj) && !isIndex {
+		// arrays only have indexes, the path isn't there
+		return obj
+	}
+	if _, ok := structField(obj, segment); isStruct(obj) && !ok {
+		// nor can fields be added to structs
+		return obj
+	}
+	isContainer := IsMap(obj) || IsOrderedMap(obj) || IsArray(obj) || isStruct(obj)
+	if !isContainer {
+		if !create {
+			return obj
+		}
+		if isIndex {
+			obj = []T{}
+		} else {
+			obj = map[T]T{}
+		}
+		copyOnWrite = false
+	}
+
+	child, found := pathGet(obj, segment)
+	var value T
+	keep := true
+	if len(segs) == 1 {
+		value, keep = fn(child, found)
+	} else if !found && !create {
+		return obj
+	} else {
+		value = updatePath(child, segs[1:], fn, create, copyOnWrite)
+	}
+
+	if isStruct(obj) {
+		return setStructField(obj, segment, value, keep, copyOnWrite)
+	}
+	if IsArray(obj) {
+		i, _ := pathIndex(segment)
+		list := obj.([]T)
+		if copyOnWrite {
+			list = Clone(list).([]T)
+		}
+		if !keep {
+			if i < len(list) {
+				list = append(list[:i:i], list[i+1:]...)
+			}
+			return list
+		}
+		for i >= len(list) {
+			list = append(list, nil)
+		}
+		list[i] = value
+		return list
+	}
+	if IsOrderedMap(obj) {
+		om := obj.(*OrderedMap)
+		if copyOnWrite {
+			om = om.Clone()
+		}
+		if keep {
+			om.Set(segment, value)
+		} else {
+			om.Delete(segment)
+		}
+		return om
+	}
+	m := obj.(map[T]T)
+	if copyOnWrite {
+		m = Clone(m).(map[T]T)
+	}
+	if keep {
+		m[segment] = value
+	} else {
+		delete(m, segment)
+	}
+	return m
+}
+
+// Get the value at a path in nested maps (map[T]T or *OrderedMap) and arrays,
+// ie Get(doc, "users.0.name") or Get(doc, []T{"users", 0, "name"}).
+// Exported struct fields are found by name too.
+// Returns opt_default, or nil, when the path isn't there.
+func Get(obj T, path T, opt_default ...T) T {
+	var value T = obj
+	found := true
+	for _, segment := range pathSegments(path) {
+		if value, found = pathGet(value, segment); !found {
+			break
+		}
+	}
+	if !found {
+		if len(opt_default) > 0 {
+			return opt_default[0]
+		}
+		return nil
+	}
+	return value
+}
+
+// Set the value at a path in nested maps and arrays, creating missing maps
+// (or arrays, when the next segment is an index) along the way.
+// Scalars in the way are replaced, but an array reached with a segment that isn't
+// an index, ie "l.foo", is left as it is.  Exported struct fields can be set too, though
+// a struct that isn't behind a pointer is copied, and only numbers are converted to the field's type.
+// Changes obj in place, but returns it as arrays that need to grow, or a nil obj, are replaced.
+// See SetCopy to leave obj unchanged.
+func Set(obj T, path T, value T) T {
+	return Update(obj, path, func(T) T { return value })
+}
+
+// Like Set, but returns a modified copy and leaves obj unchanged.
+// Only the maps and arrays along the path are copied, the rest is shared.
+func SetCopy(obj T, path T, value T) T {
+	return UpdateCopy(obj, path, func(T) T { return value })
+}
+
+// Replace the value at a path with fn(oldValue), creating the path like Set does.
+// fn gets nil when the path isn't there.
+func Update(obj T, path T, fn func(T) T) T {
+	segs := pathSegments(path)
+	if len(segs) == 0 {
+		return fn(obj)
+	}
+	return updatePath(obj, segs, func(old T, found bool) (T, bool) { return fn(old), true }, true, false)
+}
+
+// Like Update, but returns a modified copy and leaves obj unchanged.
+func UpdateCopy(obj T, path T, fn func(T) T) T {
+	segs := pathSegments(path)
+	if len(segs) == 0 {
+		return fn(obj)
+	}
+	return updatePath(obj, segs, func(old T, found bool) (T, bool) { return fn(old), true }, true, true)
+}
+
+// Remove the key (or array element) at a path.  Missing paths are left alone.
+// Changes obj in place, but returns it as arrays are shortened.
+func Unset(obj T, path T) T {
+	segs := pathSegments(path)
+	if len(segs) == 0 {
+		return obj
+	}
+	return updatePath(obj, segs, func(old T, found bool) (T, bool) { return nil, false }, false, false)
+}
+
+// Like Unset, but returns a modified copy and leaves obj unchanged.
+func UnsetCopy(obj T, path T) T {
+	segs := pathSegments(path)
+	if len(segs) == 0 {
+		return obj
+	}
+	return updatePath(obj, segs, func(old T, found bool) (T, bool) { return nil, false }, false, true)
+}
+
+// Options for FlattenMap and UnflattenMap.  The zero value joins keys with "."
+// and flattens every level.
+type FlattenOptions struct {
+	// Joins the keys of each level, "." if empty
+	Separator string
+	// How many levels of nesting to flatten, nested values past it are kept whole.
+	// 0 flattens every level.  UnflattenMap splits keys into at most this many levels
+	MaxDepth int
+	// UnflattenMap builds maps only, rather than an array for each level keyed 0, 1, 2...
+	NoArrays bool
+}
+
+// Internal function to fill in FlattenOptions defaults
+func flattenOptions(opt_options []FlattenOptions) FlattenOptions {
+	var options FlattenOptions
+	if len(opt_options) > 0 {
+		options = opt_options[0]
+	}
+	if options.Separator == "" {
+		options.Separator = "."
+	}
+	return options
+}
+
+// Flatten nested maps (map[T]T or *OrderedMap) and arrays into a single level map
+// with dotted keys, ie map[a:map[b:[x y]]] -> map[a.b.0:x a.b.1:y].
+// Empty nested maps and arrays are kept as values, so UnflattenMap can restore them.
+func FlattenMap(obj T, opt_options ...FlattenOptions) map[string]T {
+	options := flattenOptions(opt_options)
+	retval := map[string]T{}
+	flattenMap(obj, "", 1, options, retval)
+	return retval
+}
+
+// Internal implementation of a recursive `flattenMap` function.
+func flattenMap(obj T, prefix string, depth int, options FlattenOptions, output map[string]T) {
+	Each(obj, func(value, key, list T) bool {
+		path := fmt.Sprint(key)
+		if prefix != "" {
+			path = prefix + options.Separator + path
+		}
+		isNested := (IsMap(value) || IsOrderedMap(value) || IsArray(value)) && !IsEmpty(value)
+		if isNested && (options.MaxDepth == 0 || depth < options.MaxDepth) {
+			flattenMap(value, path, depth+1, options, output)
+		} else {
+			output[path] = value
+		}
+		return eachContinue
+	})
+}
+
+// Rebuild nested maps and arrays from a map with dotted keys, the inverse of FlattenMap.
+// A level whose keys are exactly 0, 1, 2... becomes an array, any other level stays a map,
+// so {"ids.100": x} is map[ids:map[100:x]], and arrays are never larger than the input.
+// A map keyed 0, 1, 2... so comes back as an array, unless NoArrays is set.  Pass the
+// MaxDepth the map was flattened with, so keys past it that hold the separator aren't split.
+func UnflattenMap(flat map[string]T, opt_options ...FlattenOptions) map[T]T {
+	options := flattenOptions(opt_options)
+	limit := -1
+	if options.MaxDepth > 0 {
+		limit = options.MaxDepth
+	}
+	root := unflattenLevel{}
+	for path, value := range flat {
+		level := root
+		segs := strings.SplitN(path, options.Separator, limit)
+		for _, segment := range segs[:len(segs)-1] {
+			child, ok := level[segment].(unflattenLevel)
+			if !ok {
+				child = unflattenLevel{}
+				level[segment] = child
+			}
+			level = child
+		}
+		if _, isLevel := level[segs[len(segs)-1]].(unflattenLevel); !isLevel {
+			level[segs[len(segs)-1]] = value
+		}
+	}
+	retval := map[T]T{}
+	for segment, value := range root {
+		retval[segment] = unflattenValue(value, options)
+	}
+	return retval
+}
+
+// Internal type, a level of nesting being rebuilt by UnflattenMap, keyed by path segment
+type unflattenLevel map[string]T
+
+// Internal function, the level as an array if its keys are the indexes 0 to len-1
+// and options allow arrays, or else a map
+func (this unflattenLevel) unflatten(options FlattenOptions) T {
+	list := make([]T, len(this))
+	for i := range list {
+		value, ok := this[strconv.Itoa(i)]
+		if !ok || options.NoArrays {
+			m := map[T]T{}
+			for segment, value := range this {
+				m[segment] = unflattenValue(value, options)
+			}
+			return m
+		}
+		list[i] = unflattenValue(value, options)
+	}
+	return list
+}
+
+// Internal function to rebuild a value that might be a level
+func unflattenValue(value T, options FlattenOptions) T {
+	if level, ok := value.(unflattenLevel); ok {
+		return level.unflatten(options)
+	}
+	return value
+}
+
+// One difference found by Diff, at Path, a list of the map keys and array indexes
+// leading to the value
+type Change struct {
+	// "add", "remove" or "replace", named after the JSON Patch ops
+	Op   string
+	Path []T
+	// The old value, for remove and replace
+	From T
+	// The new value, for add and replace
+	Value T
+}
+
+// Internal function for listing keys in a stable order: an *OrderedMap's own order,
+// or sorted by their string form for native maps
+func sortedKeys(obj T) []T {
+	keys := Keys(obj)
+	if IsMap(obj) {
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+	}
+	return keys
+}
+
+// Internal function to append a segment to a copy of a path
+func appendPath(path []T, segment T) []T {
+	retval := make([]T, len(path), len(path)+1)
+	copy(retval, path)
+	return append(retval, segment)
+}
+
+// Structurally compare two values, walking nested maps (map[T]T or *OrderedMap),
+// arrays and structs, and return what changed from a to b.
+// Map keys are visited in a stable order; trailing array elements are removed
+// from the highest index down, so the changes can be applied in order, see Patch.
+// Cyclic references are compared once, rather than walked forever.
+func Diff(a T, b T) []Change {
+	return diff(a, b, []T{}, make([]Change, 0), map[diffSeenKey]bool{})
+}
+
+// A pair of references Diff is already comparing, so cycles are only walked once
+type diffSeenKey struct {
+	a, b cloneSeenKey
+}
+
+// Internal function, the key for a map, slice or pointer as CloneDeep remembers it
+func referenceKey(obj T) (cloneSeenKey, bool) {
+	v := reflect.ValueOf(obj)
+	switch v.Kind() {
+	case reflect.Map, reflect.Ptr:
+		if !v.IsNil() {
+			return cloneSeenKey{v.Type(), v.Pointer(), 0}, true
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			return cloneSeenKey{v.Type(), v.Pointer(), v.Len()}, true
+		}
+	}
+	return cloneSeenKey{}, false
+}
+
+// Internal implementation of a recursive `diff` function.
+func diff(a T, b T, path []T, changes []Change, seen map[diffSeenKey]bool) []Change {
+	keyA, refA := referenceKey(a)
+	keyB, refB := referenceKey(b)
+	if refA && refB {
+		if seen[diffSeenKey{keyA, keyB}] {
+			return changes
+		}
+		seen[diffSeenKey{keyA, keyB}] = true
+	}
+	isMapA := IsMap(a) || IsOrderedMap(a)
+	isMapB := IsMap(b) || IsOrderedMap(b)
+	if isMapA && isMapB {
+		for _, k := range sortedKeys(a) {
+			valueA, _ := getKey(a, k)
+			if valueB, ok := getKey(b, k); ok {
+				changes = diff(valueA, valueB, appendPath(path, k), changes, seen)
+			} else {
+				changes = append(changes, Change{Op: "remove", Path: appendPath(path, k), From: valueA})
+			}
+		}
+		for _, k := range sortedKeys(b) {
+			if !Has(a, k) {
+				valueB, _ := getKey(b, k)
+				changes = append(changes, Change{Op: "add", Path: appendPath(path, k), Value: valueB})
+			}
+		}
+		return changes
+	}
+	if IsArray(a) && IsArray(b) {
+		listA, listB := a.([]T), b.([]T)
+		for i := 0; i < len(listA) && i < len(listB); i++ {
+			changes = diff(listA[i], listB[i], appendPath(path, i), changes, seen)
+		}
+		for i := len(listA) - 1; i >= len(listB); i-- {
+			changes = append(changes, Change{Op: "remove", Path: appendPath(path, i), From: listA[i]})
+		}
+		for i := len(listA); i < len(listB); i++ {
+			changes = append(changes, Change{Op: "add", Path: appendPath(path, i), Value: listB[i]})
+		}
+		return changes
+	}
+	if a != nil && b != nil {
+		// a nil pointer on either side is replaced as a whole, below
+		va, vb := reflect.Indirect(reflect.ValueOf(a)), reflect.Indirect(reflect.ValueOf(b))
+		if va.IsValid() && vb.IsValid() && va.Kind() == reflect.Struct && va.Type() == vb.Type() {
+			for i := 0; i < va.NumField(); i++ {
+				if va.Type().Field(i).PkgPath != "" {
+					continue
+				}
+				name := va.Type().Field(i).Name
+				changes = diff(va.Field(i).Interface(), vb.Field(i).Interface(), appendPath(path, name), changes, seen)
+			}
+			return changes
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		changes = append(changes, Change{Op: "replace", Path: path, From: a, Value: b})
+	}
+	return changes
+}
+
+// One RFC 6902 JSON Patch operation
+type PatchOp struct {
+	// "add", "remove", "replace", "move", "copy" or "test"
+	Op string `json:"op"`
+	// A JSON Pointer (RFC 6901), ie "/users/0/name"
+	Path string `json:"path"`
+	// A JSON Pointer to the source, for move and copy
+	From string `json:"from,omitempty"`
+	// The value, for add, replace and test
+	Value T `json:"value,omitempty"`
+}
+
+// Implement json.Marshaler, so add, replace and test ops keep a null value,
+// and native map[T]T values can be encoded
+func (this PatchOp) MarshalJSON() ([]byte, error) {
+	fields := NewOrderedMap("op", this.Op, "path", this.Path)
+	if this.Op == "move" || this.Op == "copy" {
+		fields.Set("from", this.From)
+	}
+	if this.Op == "add" || this.Op == "replace" || this.Op == "test" {
+		fields.Set("value", this.Value)
+	}
+	return fields.MarshalJSON()
+}
+
+// Convert a path of keys and indexes into a JSON Pointer (RFC 6901), ie []T{"a", 0} -> "/a/0"
+func JSONPointer(path []T) string {
+	var buf bytes.Buffer
+	for _, segment := range path {
+		buf.WriteString("/")
+		buf.WriteString(strings.Replace(strings.Replace(fmt.Sprint(segment), "~", "~0", -1), "/", "~1", -1))
+	}
+	return buf.String()
+}
+
+// Convert the changes found by Diff into RFC 6902 JSON Patch operations
+func JSONPatch(changes []Change) []PatchOp {
+	ops := make([]PatchOp, len(changes))
+	for i, change := range changes {
+		ops[i] = PatchOp{Op: change.Op, Path: JSONPointer(change.Path)}
+		if change.Op != "remove" {
+			ops[i].Value = change.Value
+		}
+	}
+	return ops
+}
+
+// Internal function to split a JSON Pointer into its unescaped tokens
+func pointerTokens(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("Patch: invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// Internal function to match a JSON Pointer token to a map's key, which might not be a string
+func pointerKey(obj T, token string) T {
+	if Has(obj, token) {
+		return token
+	}
+	for _, k := range Keys(obj) {
+		if fmt.Sprint(k) == token {
+			return k
+		}
+	}
+	return token
+}
+
+// Internal function to resolve a JSON Pointer's tokens into a path of keys and indexes,
+// returning the value found there
+func resolvePointer(obj T, tokens []string) ([]T, T, bool) {
+	path := make([]T, 0, len(tokens))
+	value := obj
+	found := true
+	for _, token := range tokens {
+		var segment T = token
+		if IsMap(value) || IsOrderedMap(value) {
+			segment = pointerKey(value, token)
+		}
+		path = append(path, segment)
+		if found {
+			value, found = pathGet(value, segment)
+		}
+	}
+	return path, value, found
+}
+
+// Internal function to apply a JSON Patch add, which inserts into arrays
+func patchAdd(obj T, tokens []string, value T) (T, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parentPath, parent, found := resolvePointer(obj, tokens[:len(tokens)-1])
+	last := tokens[len(tokens)-1]
+	if !found {
+		return nil, fmt.Errorf("Patch: path %q does not exist", JSONPointer(parentPath))
+	}
+	if IsArray(parent) {
+		list := parent.([]T)
+		i, ok := pathIndex(last)
+		if last == "-" {
+			i, ok = len(list), true
+		}
+		if !ok || i > len(list) {
+			return nil, fmt.Errorf("Patch: bad array index %q", last)
+		}
+		inserted := make([]T, 0, len(list)+1)
+		inserted = append(append(append(inserted, list[:i]...), value), list[i:]...)
+		return SetCopy(obj, parentPath, inserted), nil
+	}
+	if IsMap(parent) || IsOrderedMap(parent) {
+		return SetCopy(obj, appendPath(parentPath, pointerKey(parent, last)), value), nil
+	}
+	if isStruct(parent) {
+		return patchSet(obj, appendPath(parentPath, last), value)
+	}
+	return nil, fmt.Errorf("Patch: can't add to %v", parent)
+}
+
+// Internal function to set a path for Patch, checking a struct field there takes the value
+func patchSet(obj T, path []T, value T) (T, error) {
+	patched := SetCopy(obj, path, value)
+	if len(path) == 0 {
+		return patched, nil
+	}
+	if parent := Get(obj, path[:len(path)-1]); isStruct(parent) {
+		if _, ok := structField(parent, path[len(path)-1]); !ok {
+			return nil, fmt.Errorf("Patch: path %q does not exist", JSONPointer(path))
+		}
+		if value != nil && !patchEqual(Get(patched, path), value) {
+			return nil, fmt.Errorf("Patch: can't set %q to %v", JSONPointer(path), value)
+		}
+	}
+	return patched, nil
+}
+
+// Internal function to compare JSON-ish values, allowing for ints decoded as float64
+func patchEqual(a T, b T) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	ja, errA := json.Marshal(jsonValue(a))
+	jb, errB := json.Marshal(jsonValue(b))
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// Internal function to turn objects and arrays decoded by encoding/json, map[string]interface{}
+// and []interface{}, into the map[T]T and []T the other functions walk
+func patchValue(value T) T {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		retval := make(map[T]T, len(v))
+		for k, elem := range v {
+			retval[k] = patchValue(elem)
+		}
+		return retval
+	case []interface{}:
+		retval := make([]T, len(v))
+		for i, elem := range v {
+			retval[i] = patchValue(elem)
+		}
+		return retval
+	case []T:
+		retval := make([]T, len(v))
+		for i, elem := range v {
+			retval[i] = patchValue(elem)
+		}
+		return retval
+	}
+	return value
+}
+
+// Apply RFC 6902 JSON Patch operations, ie from JSONPatch(Diff(a, b)), to nested
+// maps (map[T]T or *OrderedMap), arrays and structs' exported fields.  Returns a patched copy, obj is unchanged.
+// Values decoded from JSON, map[string]interface{} and []interface{}, are added as map[T]T and []T,
+// so later ops can reach into them.
+// Struct fields can't be added or removed, removing one zeroes it.
+// If an operation fails, or a test op doesn't match, the error says which and obj is returned.
+func Patch(obj T, ops []PatchOp) (T, error) {
+	doc := obj
+	for i, op := range ops {
+		tokens, err := pointerTokens(op.Path)
+		if err != nil {
+			return obj, err
+		}
+		path, current, found := resolvePointer(doc, tokens)
+		opValue := patchValue(op.Value)
+		switch op.Op {
+		case "add":
+			doc, err = patchAdd(doc, tokens, opValue)
+		case "remove", "replace":
+			if !found {
+				err = fmt.Errorf("Patch: path %q does not exist", op.Path)
+			} else if len(tokens) == 0 && op.Op == "remove" {
+				doc = nil
+			} else if op.Op == "remove" {
+				doc = UnsetCopy(doc, path)
+			} else {
+				doc, err = patchSet(doc, path, opValue)
+			}
+		case "move", "copy":
+			fromTokens, fromErr := pointerTokens(op.From)
+			fromPath, value, fromFound := resolvePointer(doc, fromTokens)
+			if fromErr != nil {
+				err = fromErr
+			} else if !fromFound {
+				err = fmt.Errorf("Patch: from path %q does not exist", op.From)
+			} else if op.Op == "move" && strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+				err = fmt.Errorf("Patch: can't move %q into itself", op.From)
+			} else if op.Op == "move" {
+				doc, err = patchAdd(UnsetCopy(doc, fromPath), tokens, value)
+			} else {
+				doc, err = patchAdd(doc, tokens, CloneDeep(value))
+			}
+		case "test":
+			if !found || !patchEqual(current, op.Value) {
+				err = fmt.Errorf("Patch: test failed at %q", op.Path)
+			}
+		default:
+			err = fmt.Errorf("Patch: unknown op %q", op.Op)
+		}
+		if err != nil {
+			return obj, fmt.Errorf("%v (op %d)", err, i)
+		}
+	}
+	return doc, nil
+}
+
 // TODO: missing Matches
 //matches_.matches(attrs) 
 //Returns a predicate function that will tell you if a passed in object contains all of the key/value properties present in attrs.
@@ -1644,10 +3846,53 @@ func Has(obj T, key T) bool {
 
 
 
-// Add a "chain" function, which will delegate to the wrapper.
+// Functions added to the *Underscore chain with Mixin, called with the wrapped value first
+var mixins = struct {
+	sync.RWMutex
+	fns map[string]func(obj T, args ...T) T
+}{fns: map[string]func(obj T, args ...T) T{}}
+
+// Add your own function to the *Underscore chain, callable by name with Call,
+// ie Mixin("capitalize", func(obj T, args ...T) T { return strings.Title(obj.(string)) })
+// New("fabio").Chain().Call("capitalize").Value() -> "Fabio"
+// Mixing in a name again replaces the function, but names of *Underscore's own
+// methods are refused with an error.
+func Mixin(name string, fn func(obj T, args ...T) T) error {
+	if _, ok := reflect.TypeOf(&Underscore{}).MethodByName(name); ok {
+		return fmt.Errorf("Mixin: %q is already a method of *Underscore", name)
+	}
+	if fn == nil {
+		return fmt.Errorf("Mixin: %q has a nil function", name)
+	}
+	mixins.Lock()
+	defer mixins.Unlock()
+	mixins.fns[name] = fn
+	return nil
+}
+
+// Call a function added with Mixin, passing the wrapped value and args, and continue chaining its result.
+// Panics if nothing was mixed in as name.
+func (this *Underscore) Call(name string, args ...T) *Underscore {
+	mixins.RLock()
+	fn, ok := mixins.fns[name]
+	mixins.RUnlock()
+	if !ok {
+		panic(fmt.Sprintf("Call: no function was mixed in as %q", name))
+	}
+	return this.result(fn(this.wrapped, args...))
+}
+
+// Pass the wrapped value through fn and continue chaining its result, for one-off
+// steps that don't need registering with Mixin.  Unlike Tap, fn's result is kept.
+func (this *Underscore) Pipe(fn func(T) T) *Underscore {
+	return this.result(fn(this.wrapped))
+}
+
+// A chained wrapper of the same value, so are the wrappers its methods return.  As every method
+// returns a new wrapper of its result either way, this is for underscore.js compatibility,
+// ie New(list).Chain().Map(f).Value() is the same as New(list).Map(f).Value()
 func (this *Underscore) Chain() *Underscore {
-	this.ischained = true
-	return this
+	return &Underscore{ischained: true, wrapped: this.wrapped}
 }
 func (this *Underscore) Max(lessThan func(T, T) bool) *Underscore {
 	return this.result(Max(lessThan, this.wrapped.([]T)...))
@@ -1656,22 +3901,73 @@ func (this *Underscore) Tap(fn func(...T) T) *Underscore {
 	Tap(this.wrapped, fn)
 	return this
 }
-func (this *Underscore) Value() T {
-	return this.wrapped
+// The wrapped value, ie the result of the last method called, chained or not.
+// It isn't copied, and may be shared with other wrappers, so Clone it before making changes
+func (this *Underscore) Value() T {
+	return this.wrapped
+}
+
+func (this *Underscore) IsFinite() *Underscore {
+	v, _ := this.wrapped.(float64)
+	return this.result(!math.IsNaN(v) && !math.IsInf(v, 1) && !math.IsInf(v, -1))
+}
+
+func (this *Underscore) IsNaN() *Underscore {
+	v, _ := this.wrapped.(float64)
+	return this.result(math.IsNaN(v))
+}
+
+func (this *Underscore) Has(key T) *Underscore {
+	return this.result(Has(this.wrapped, key))
+}
+
+// OOP-style support, add method to *Underscore, see func Diff
+func (this *Underscore) Diff(other T) *Underscore {
+	return this.result(Diff(this.wrapped, other))
+}
+
+// OOP-style support, add method to *Underscore, see func Patch
+// The wrapped value is left unchanged.  Panics if an op fails
+func (this *Underscore) Patch(ops []PatchOp) *Underscore {
+	v, err := Patch(this.wrapped, ops)
+	if err != nil {
+		panic(err.Error())
+	}
+	return this.result(v)
+}
+
+// OOP-style support, add method to *Underscore, see func FlattenMap
+func (this *Underscore) FlattenMap(opt_options ...FlattenOptions) *Underscore {
+	return this.result(FlattenMap(this.wrapped, opt_options...))
+}
+
+// OOP-style support, add method to *Underscore, see func UnflattenMap
+func (this *Underscore) UnflattenMap(opt_options ...FlattenOptions) *Underscore {
+	v, _ := this.wrapped.(map[string]T)
+	return this.result(UnflattenMap(v, opt_options...))
 }
 
-func (this *Underscore) IsFinite() *Underscore {
-	v, _ := this.wrapped.(float64)
-	return this.result(!math.IsNaN(v) && !math.IsInf(v, 1) && !math.IsInf(v, -1))
+// OOP-style support, add method to *Underscore, see func Get
+func (this *Underscore) Get(path T, opt_default ...T) *Underscore {
+	return this.result(Get(this.wrapped, path, opt_default...))
 }
 
-func (this *Underscore) IsNaN() *Underscore {
-	v, _ := this.wrapped.(float64)
-	return this.result(math.IsNaN(v))
+// OOP-style support, add method to *Underscore, see func SetCopy
+// The wrapped value is left unchanged
+func (this *Underscore) Set(path T, value T) *Underscore {
+	return this.result(SetCopy(this.wrapped, path, value))
 }
 
-func (this *Underscore) Has(key T) *Underscore {
-	return this.result(Has(this.wrapped, key))
+// OOP-style support, add method to *Underscore, see func UpdateCopy
+// The wrapped value is left unchanged
+func (this *Underscore) Update(path T, fn func(T) T) *Underscore {
+	return this.result(UpdateCopy(this.wrapped, path, fn))
+}
+
+// OOP-style support, add method to *Underscore, see func UnsetCopy
+// The wrapped value is left unchanged
+func (this *Underscore) Unset(path T) *Underscore {
+	return this.result(UnsetCopy(this.wrapped, path))
 }
 
 // Utility Functions
@@ -1681,9 +3977,6 @@ func (this *Underscore) Has(key T) *Underscore {
 func IdentityEach(val T, index T, list T) bool {
 	return val == val
 }
-func identityHasher(val ...T) T {
-	return val[0]
-}
 
 func IdentityIsTruthy(val T, index T, list T) bool {
 	v, ok := val.(bool)
@@ -1746,6 +4039,12 @@ func IsMap(obj T) bool {
 	return v != nil
 }
 
+// Is a given variable an *OrderedMap
+func IsOrderedMap(obj T) bool {
+	v, _ := obj.(*OrderedMap)
+	return v != nil
+}
+
 // Is a given value an array?
 func IsFunction(obj T) bool {
 	v, _ := obj.(func(T, T, T) T)
@@ -1770,6 +4069,9 @@ func IsEmpty(obj T) bool {
 	if IsMap(obj) {
 		return len(obj.(map[T]T)) == 0
 	}
+	if IsOrderedMap(obj) {
+		return obj.(*OrderedMap).Len() == 0
+	}
 	if IsString(obj) {
 		return len(obj.(string)) == 0
 	}
@@ -1823,7 +4125,7 @@ func Result(obj, propertyName T) T {
 	if obj == nil {
 		return nil
 	}
-	val := obj.(map[T]T)[propertyName]
+	val, _ := getKey(obj, propertyName)
 	if IsFunctionVariadic(val) { // func(...T) T
 		return val.(func(...T) T)(obj)
 	}
@@ -1846,6 +4148,183 @@ func (this *Underscore) RandomFloat64(min float64, optmax ...float64) float64 {
 	return RandomFloat64(min, optmax...)
 }
 
+// Makes the part of a generated id that follows the prefix.  An IdGenerator calls
+// Next and Reset with its lock held, so strategies needn't be goroutine-safe themselves.
+type IdStrategy interface {
+	Next() string
+	// Start over, ie so tests get the same ids every run
+	Reset()
+}
+
+// An IdStrategy counting up from 1: "1", "2", "3", ...
+type CounterIds struct {
+	count int64
+}
+
+func (this *CounterIds) Next() string {
+	this.count += 1
+	return strconv.FormatInt(this.count, 10)
+}
+
+func (this *CounterIds) Reset() {
+	this.count = 0
+}
+
+// An IdStrategy using the current time in nanoseconds, bumped when need be so ids
+// are always increasing, even when the clock doesn't move between calls
+type TimeIds struct {
+	// Defaults to DefaultClock
+	Clock Clock
+	last  int64
+}
+
+func (this *TimeIds) Next() string {
+	now := clockOrDefault(this.Clock).Now().UnixNano()
+	if now <= this.last {
+		now = this.last + 1
+	}
+	this.last = now
+	return strconv.FormatInt(now, 10)
+}
+
+func (this *TimeIds) Reset() {
+	this.last = 0
+}
+
+// An IdStrategy making random, version 4 UUIDs, ie "1b4e28ba-2fa1-41d2-883f-0016d3cca427",
+// from crypto/rand.  Reset does nothing.
+type RandomIds struct{}
+
+func (this *RandomIds) Next() string {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		for i := range b {
+			b[i] = byte(rand.Intn(256))
+		}
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func (this *RandomIds) Reset() {}
+
+// Generates prefixed ids, safe for use from multiple goroutines
+type IdGenerator struct {
+	mu       sync.Mutex
+	strategy IdStrategy
+}
+
+// Create an IdGenerator using strategy, ie NewIdGenerator(new(RandomIds))
+func NewIdGenerator(strategy IdStrategy) *IdGenerator {
+	this := new(IdGenerator)
+	this.strategy = strategy
+	return this
+}
+
+// Generate the next id, prefixed with prefix if given
+func (this *IdGenerator) Next(prefix ...string) string {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return strings.Join(prefix, "") + this.strategy.Next()
+}
+
+// Start the generator over, ie in a test's setup
+func (this *IdGenerator) Reset() {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.strategy.Reset()
+}
+
+// The IdGenerator used by UniqueId, counting up from 1
+var UniqueIds = NewIdGenerator(new(CounterIds))
+
+// Generate a unique integer id (unique within the entire process), prefixed if
+// a prefix is given.  Useful for temporary DOM ids, or keys for records built with Object and IndexBy.
+//
+//	UniqueId("contact_") -> "contact_1"
+func UniqueId(prefix ...string) string {
+	return UniqueIds.Next(prefix...)
+}
+
+// OOP-style support, add method to *Underscore, see func UniqueId
+// A wrapped string is used as the prefix
+func (this *Underscore) UniqueId() *Underscore {
+	prefix, _ := this.wrapped.(string)
+	return this.result(UniqueId(prefix))
+}
+
+// List of HTML entities for escaping, the same as Underscore.js's escapeMap
+var escapeMap = map[string]string{
+	"&": "&amp;",
+	"<": "&lt;",
+	">": "&gt;",
+	`"`: "&quot;",
+	"'": "&#x27;",
+	"`": "&#x60;",
+}
+
+// Replacers for Escape and Unescape, built from escapeMap
+var escaper, unescaper = func() (*strings.Replacer, *strings.Replacer) {
+	escapes := make([]string, 0)
+	unescapes := make([]string, 0)
+	for char, entity := range escapeMap {
+		escapes = append(escapes, char, entity)
+		unescapes = append(unescapes, entity, char)
+	}
+	return strings.NewReplacer(escapes...), strings.NewReplacer(unescapes...)
+}()
+
+// Internal function to apply a replacer to a string, or recursively to the
+// strings in []T, map[T]T and *OrderedMap values, returning copies
+func replaceStrings(obj T, replacer *strings.Replacer) T {
+	if s, ok := obj.(string); ok {
+		return replacer.Replace(s)
+	}
+	if IsArray(obj) {
+		return Map(obj, func(value, index, list T) T {
+			return replaceStrings(value, replacer)
+		})
+	}
+	if IsMap(obj) {
+		retval := make(map[T]T, len(obj.(map[T]T)))
+		for k, v := range obj.(map[T]T) {
+			retval[k] = replaceStrings(v, replacer)
+		}
+		return retval
+	}
+	if IsOrderedMap(obj) {
+		retval := new(OrderedMap)
+		obj.(*OrderedMap).Each(func(value, key, list T) bool {
+			retval.Set(key, replaceStrings(value, replacer))
+			return eachContinue
+		})
+		return retval
+	}
+	return obj
+}
+
+// Escape a string for insertion into HTML, replacing &, <, >, ", ' and ` characters.
+// Escapes the strings in []T, map[T]T and *OrderedMap values recursively, other values are returned as is.
+func Escape(obj T) T {
+	return replaceStrings(obj, escaper)
+}
+
+// The opposite of Escape, replaces &amp;, &lt;, &gt;, &quot;, &#x27; and &#x60; with their unescaped counterparts.
+func Unescape(obj T) T {
+	return replaceStrings(obj, unescaper)
+}
+
+// OOP-style support, add method to *Underscore, see func Escape
+func (this *Underscore) Escape() *Underscore {
+	return this.result(Escape(this.wrapped))
+}
+
+// OOP-style support, add method to *Underscore, see func Unescape
+func (this *Underscore) Unescape() *Underscore {
+	return this.result(Unescape(this.wrapped))
+}
+
 // OOP-style funcs for Underscore
 
 // OOP-style support, add method to *Underscore, see func Every
@@ -1950,8 +4429,9 @@ func (this *Underscore) Reverse() *Underscore {
 
 // OOP-style support, add method to *Underscore, see func Concat
 func (this *Underscore) Concat(array []T) *Underscore {
-	a := append(this.wrapped.([]T), array...)
-	return this.result(a)
+	v := this.wrapped.([]T)
+	a := make([]T, 0, len(v)+len(array))
+	return this.result(append(append(a, v...), array...))
 }
 
 // OOP-style support, add method to *Underscore, see func Difference
@@ -1968,23 +4448,224 @@ func (this *Underscore) Drop() *Underscore {
 
 // OOP-style support, add method to *Underscore, see func Unshift
 func (this *Underscore) Unshift(elems ...T) *Underscore {
-	for _, v := range this.wrapped.([]T) {
-		elems = append(elems, v)
-	}
-	return this.result(elems)
+	v := this.wrapped.([]T)
+	a := make([]T, 0, len(elems)+len(v))
+	return this.result(append(append(a, elems...), v...))
 }
 
-// OOP-style support, add method to *Underscore, see func Shift
+// JS Array methods on the wrapper.  As wrappers are immutable, methods that change the array
+// in JS (Push, Unshift, Splice, Sort, Fill, Reverse) continue with a changed copy of it, while Pop
+// and Shift continue with what JS returns, the removed element, as lodash's wrapper does.
+// Indexes can be negative, counting back from the end.  See also Contains for Includes
+// without JS's NaN handling, and IndexOf for underscore's sorted search.
+
+// The first element, like Array.prototype.shift, or nil for an empty array
 func (this *Underscore) Shift() *Underscore {
 	a, _ := this.wrapped.([]T)
+	if len(a) == 0 {
+		return this.result(nil)
+	}
 	return this.result(a[0])
 }
 
-// OOP-style support, add method to *Underscore, see func Pop
+// The last element, like Array.prototype.pop, or nil for an empty array
 func (this *Underscore) Pop() *Underscore {
 	a, _ := this.wrapped.([]T)
-	retval := a[:len(a)-1]
-	return this.result(retval)
+	if len(a) == 0 {
+		return this.result(nil)
+	}
+	return this.result(a[len(a)-1])
+}
+
+// Add elements to the end, like Array.prototype.push
+func (this *Underscore) Push(elems ...T) *Underscore {
+	a, _ := this.wrapped.([]T)
+	return this.result(append(append(make([]T, 0, len(a)+len(elems)), a...), elems...))
+}
+
+// Internal function, a JS-style index: negative counts back from the end, clamped to [0, length]
+func relativeIndex(index int, length int) int {
+	if index < 0 {
+		index += length
+		if index < 0 {
+			return 0
+		}
+	}
+	if index > length {
+		return length
+	}
+	return index
+}
+
+// Internal function, the start and end of a JS-style range, from the optional start and end given
+func relativeRange(opt_start_end []int, length int) (int, int) {
+	start, end := 0, length
+	if len(opt_start_end) > 0 {
+		start = relativeIndex(opt_start_end[0], length)
+	}
+	if len(opt_start_end) > 1 {
+		end = relativeIndex(opt_start_end[1], length)
+	}
+	if end < start {
+		end = start
+	}
+	return start, end
+}
+
+// A copy of the elements from start up to, but not including, end, like Array.prototype.slice,
+// ie New(list).Slice(-2) is the last two elements.  Both default to the whole array
+func (this *Underscore) Slice(opt_start_end ...int) *Underscore {
+	a, _ := this.wrapped.([]T)
+	start, end := relativeRange(opt_start_end, len(a))
+	return this.result(append([]T{}, a[start:end]...))
+}
+
+// A copy with deleteCount elements from start replaced by items, like Array.prototype.splice.
+// JS's splice returns the removed elements, here they're New(list).Slice(start, start+deleteCount)
+func (this *Underscore) Splice(start int, deleteCount int, items ...T) *Underscore {
+	a, _ := this.wrapped.([]T)
+	start = relativeIndex(start, len(a))
+	end := relativeIndex(start+MaxInt(deleteCount, 0), len(a))
+	spliced := make([]T, 0, len(a)-(end-start)+len(items))
+	spliced = append(append(append(spliced, a[:start]...), items...), a[end:]...)
+	return this.result(spliced)
+}
+
+// A sorted copy, like Array.prototype.sort.  compare returns a negative number if a comes first,
+// positive if b does, and 0 to keep their order.  By default elements are compared as strings,
+// as in JS, so 10 comes before 9.  nil elements always go last
+func (this *Underscore) Sort(opt_compare ...func(a, b T) int) *Underscore {
+	a, _ := this.wrapped.([]T)
+	sorted := append([]T{}, a...)
+	compare := func(a, b T) int { return strings.Compare(jsString(a), jsString(b)) }
+	if len(opt_compare) > 0 && opt_compare[0] != nil {
+		compare = opt_compare[0]
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i] == nil || sorted[j] == nil {
+			return sorted[j] == nil && sorted[i] != nil
+		}
+		return compare(sorted[i], sorted[j]) < 0
+	})
+	return this.result(sorted)
+}
+
+// Internal function, a value as JS would turn it into a string for joining or sorting:
+// nil is empty, and arrays are joined with commas
+func jsString(value T) string {
+	if value == nil {
+		return ""
+	}
+	if list, ok := value.([]T); ok {
+		strs := make([]string, len(list))
+		for i, elem := range list {
+			strs[i] = jsString(elem)
+		}
+		return strings.Join(strs, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// The elements as strings joined by separator, "," by default, like Array.prototype.join.
+// nil elements are empty, and nested arrays are joined with commas
+func (this *Underscore) Join(opt_separator ...string) *Underscore {
+	a, _ := this.wrapped.([]T)
+	separator := ","
+	if len(opt_separator) > 0 {
+		separator = opt_separator[0]
+	}
+	strs := make([]string, len(a))
+	for i, elem := range a {
+		strs[i] = jsString(elem)
+	}
+	return this.result(strings.Join(strs, separator))
+}
+
+// A copy with the elements from start up to, but not including, end set to value,
+// like Array.prototype.fill.  Both default to the whole array
+func (this *Underscore) Fill(value T, opt_start_end ...int) *Underscore {
+	a, _ := this.wrapped.([]T)
+	filled := append([]T{}, a...)
+	start, end := relativeRange(opt_start_end, len(a))
+	for i := start; i < end; i++ {
+		filled[i] = value
+	}
+	return this.result(filled)
+}
+
+// Internal function, JS's SameValueZero equality: NaN is the same as NaN, and arrays, maps
+// and funcs are only the same as themselves
+func sameValueZero(a, b T) bool {
+	if x, ok := a.(float64); ok && math.IsNaN(x) {
+		y, ok := b.(float64)
+		return ok && math.IsNaN(y)
+	}
+	if a == nil || b == nil || reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return a == b
+	}
+	if !reflect.TypeOf(a).Comparable() {
+		va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+		switch va.Kind() {
+		case reflect.Slice:
+			return va.Pointer() == vb.Pointer() && va.Len() == vb.Len()
+		case reflect.Map, reflect.Func:
+			return va.Pointer() == vb.Pointer()
+		}
+		return false
+	}
+	return a == b
+}
+
+// Whether the array has value, from fromIndex on, like Array.prototype.includes
+func (this *Underscore) Includes(value T, opt_fromIndex ...int) *Underscore {
+	a, _ := this.wrapped.([]T)
+	start := 0
+	if len(opt_fromIndex) > 0 {
+		start = relativeIndex(opt_fromIndex[0], len(a))
+	}
+	for _, elem := range a[start:] {
+		if sameValueZero(elem, value) {
+			return this.result(true)
+		}
+	}
+	return this.result(false)
+}
+
+// The index of value, searching from fromIndex on, like Array.prototype.indexOf, or -1 if it's not there.
+// Compares like JS's ===, so NaN is never found, and arrays, maps and funcs only by reference
+func (this *Underscore) IndexOfFrom(value T, opt_fromIndex ...int) *Underscore {
+	a, _ := this.wrapped.([]T)
+	start := 0
+	if len(opt_fromIndex) > 0 {
+		start = relativeIndex(opt_fromIndex[0], len(a))
+	}
+	if f, ok := value.(float64); ok && math.IsNaN(f) {
+		return this.result(-1)
+	}
+	for i := start; i < len(a); i++ {
+		if sameValueZero(a[i], value) {
+			return this.result(i)
+		}
+	}
+	return this.result(-1)
+}
+
+// Map each element through an iterator and flatten the results one level, like Array.prototype.flatMap
+func (this *Underscore) FlatMap(iterator func(T, T, T) T) *Underscore {
+	a, _ := this.wrapped.([]T)
+	return this.result(Flatten(Map(a, iterator), true))
+}
+
+// The element at index, like Array.prototype.at, or nil if there's none
+func (this *Underscore) At(index int) *Underscore {
+	a, _ := this.wrapped.([]T)
+	if index < 0 {
+		index += len(a)
+	}
+	if index < 0 || index >= len(a) {
+		return this.result(nil)
+	}
+	return this.result(a[index])
 }
 
 // OOP-style support, add method to *Underscore, see func Clone
@@ -1992,6 +4673,21 @@ func (this *Underscore) Clone() *Underscore {
 	return this.result(Clone(this.wrapped))
 }
 
+// OOP-style support, add method to *Underscore, see func CloneDeep
+func (this *Underscore) CloneDeep() *Underscore {
+	return this.result(CloneDeep(this.wrapped))
+}
+
+// OOP-style support, add method to *Underscore, see func Merge
+func (this *Underscore) Merge(objs ...T) *Underscore {
+	return this.result(Merge(append([]T{this.wrapped}, objs...)...))
+}
+
+// OOP-style support, add method to *Underscore, see func MergeDeep
+func (this *Underscore) MergeDeep(objs ...T) *Underscore {
+	return this.result(MergeDeep(append([]T{this.wrapped}, objs...)...))
+}
+
 // OOP-style support, add method to *Underscore, see func Compacts
 func (this *Underscore) Compact() *Underscore {
 	v, _ := this.wrapped.([]T)
@@ -1999,9 +4695,13 @@ func (this *Underscore) Compact() *Underscore {
 }
 
 // OOP-style support, add method to *Underscore, see func Defaults
+// The wrapped map is left unchanged
 func (this *Underscore) Defaults(args ...T) *Underscore {
+	if IsOrderedMap(this.wrapped) {
+		return this.result(this.wrapped.(*OrderedMap).Clone().Defaults(args...))
+	}
 	v, _ := this.wrapped.(map[T]T)
-	return this.result(Defaults(v, args...))
+	return this.result(Defaults(Merge(v), args...))
 }
 
 // OOP-style support, add method to *Underscore, see func Each
@@ -2035,9 +4735,13 @@ func (this *Underscore) Every(obj T, opt_iterator ...eachlistiterator) *Undersco
 }
 
 // OOP-style support, add method to *Underscore, see func Extend
+// The wrapped map is left unchanged
 func (this *Underscore) Extend(args ...T) *Underscore {
+	if IsOrderedMap(this.wrapped) {
+		return this.result(this.wrapped.(*OrderedMap).Clone().Extend(args...))
+	}
 	v, _ := this.wrapped.(map[T]T)
-	return this.result(Extend(v, args...))
+	return this.result(Merge(append([]T{v}, args...)...))
 }
 
 // OOP-style support, add method to *Underscore, see func Detect
@@ -2100,6 +4804,14 @@ func (this *Underscore) HeadN(n int, opt_guard ...bool) *Underscore {
 	return this.result(FirstN(v, n, opt_guard...))
 }
 
+// OOP-style support, add method to *Underscore, see func TakeN
+// Aliased as FirstN
+// Aliased as HeadN
+func (this *Underscore) TakeN(n int, opt_guard ...bool) *Underscore {
+	v, _ := this.wrapped.([]T)
+	return this.result(FirstN(v, n, opt_guard...))
+}
+
 // OOP-style support, add method to *Underscore, see func Contains
 // Aliased as Contains
 func (this *Underscore) Include(target T, opt_comparator ...func(T, T) bool) *Underscore {
@@ -2118,15 +4830,14 @@ func (this *Underscore) Initial(opt_n ...int) *Underscore {
 }
 
 // OOP-style support, add method to *Underscore, see func Intersection
+// The wrapped array is the first of the arrays
 func (this *Underscore) Intersection(lessThan func(T, T) bool, opt_array ...T) *Underscore {
-	v, _ := this.wrapped.([]T)
-	return this.result(Intersection(lessThan, append(v, opt_array...)))
+	return this.result(Intersection(lessThan, append([]T{this.wrapped}, opt_array...)...))
 }
 
 // OOP-style support, add method to *Underscore, see func Invert
 func (this *Underscore) Invert() *Underscore {
-	v, _ := this.wrapped.(map[T]T)
-	return this.result(Invert(v))
+	return this.result(Invert(this.wrapped))
 }
 
 // OOP-style support, add method to *Underscore, see func Invoke
@@ -2171,8 +4882,7 @@ func (this *Underscore) IsStringArray() *Underscore {
 
 // OOP-style support, add method to *Underscore, see func Keys
 func (this *Underscore) Keys() *Underscore {
-	v, _ := this.wrapped.(map[T]T)
-	return this.result(Keys(v))
+	return this.result(Keys(this.wrapped))
 }
 
 // OOP-style support, add method to *Underscore, see func Last
@@ -2208,17 +4918,17 @@ func (this *Underscore) Object() *Underscore {
 
 // OOP-style support, add method to *Underscore, see func Omit
 func (this *Underscore) Omit(keysToRemove ...T) *Underscore {
-	return this.result(Omit(this.wrapped.(map[T]T), keysToRemove...))
+	return this.result(Omit(this.wrapped, keysToRemove...))
 }
 
 // OOP-style support, add method to *Underscore, see func Pairs
 func (this *Underscore) Pairs() *Underscore {
-	return this.result(Pairs(this.wrapped.(map[T]T)))
+	return this.result(Pairs(this.wrapped))
 }
 
 // OOP-style support, add method to *Underscore, see func Pick
 func (this *Underscore) Pick(keysToKeep ...T) *Underscore {
-	return this.result(Pick(this.wrapped.(map[T]T), keysToKeep...))
+	return this.result(Pick(this.wrapped, keysToKeep...))
 }
 
 // OOP-style support, add method to *Underscore, see func Pluck
@@ -2266,37 +4976,37 @@ func (this *Underscore) ToArray() *Underscore {
 	return this.result(ToArray(this.wrapped))
 }
 
-// OOP-style support, add method to *Underscore, see func ToArray
+// OOP-style support, add method to *Underscore, see func Now
 func (this *Underscore) Now() *Underscore {
 	return this.result(Now())
 }
 
-// OOP-style support, add method to *Underscore, see func ToArray
+// OOP-style support, add method to *Underscore, see func NowNano
 func (this *Underscore) NowNano() *Underscore {
 	return this.result(NowNano())
 }
 
 // OOP-style support, add method to *Underscore, see func Union
+// The wrapped array is the first of the arrays
 func (this *Underscore) Union(opt_array ...T) *Underscore {
-	args := this.wrapped.([]T)
-	return this.result(Union(append(args, opt_array...)))
+	return this.result(Union(append([]T{this.wrapped}, opt_array...)...))
 }
 
 // OOP-style support, add method to *Underscore, see func Uniq
 // Aliased as Unique
 func (this *Underscore) Uniq(isSorted T /*bool or func*/, opt_iterator ...T) *Underscore {
-	return this.result(Uniq(this.wrapped, isSorted, opt_iterator))
+	return this.result(Uniq(this.wrapped, isSorted, opt_iterator...))
 }
 
 // OOP-style support, add method to *Underscore, see func Unique
 // Aliased as Uniq
 func (this *Underscore) Unique(isSorted T /*bool or func*/, opt_iterator ...T) *Underscore {
-	return this.result(Unique(this.wrapped, isSorted, opt_iterator))
+	return this.result(Unique(this.wrapped, isSorted, opt_iterator...))
 }
 
 // OOP-style support, add method to *Underscore, see func Values
 func (this *Underscore) Values() *Underscore {
-	return this.result(Values(this.wrapped.(map[T]T)))
+	return this.result(Values(this.wrapped))
 }
 
 // OOP-style support, add method to *Underscore, see func Without
@@ -2315,14 +5025,265 @@ func (this *Underscore) Where(attrs map[T]T, optReturnFirstFound ...bool) *Under
 }
 
 // OOP-style support, add method to *Underscore, see func Zip
-func (this *Underscore) Zip() *Underscore {
-	return this.result(Zip(this.wrapped.([]T)))
+// The wrapped array is the first of the arrays
+func (this *Underscore) Zip(arrays ...[]T) *Underscore {
+	v, _ := this.wrapped.([]T)
+	return this.result(Zip(append([][]T{v}, arrays...)...))
 }
 
-// Helper function to continue chaining intermediate results.
-func (this *Underscore) result(obj T) *Underscore {
-	if this.ischained {
-		return New(obj).Chain()
+// OOP-style support, add method to *Underscore, see func SortedIndex
+func (this *Underscore) SortedIndex(obj T, lessThan func(T, T) bool, opt_iterator ...func(T, T, T) T) *Underscore {
+	return this.result(SortedIndex(this.wrapped, obj, lessThan, opt_iterator...))
+}
+
+// OOP-style support, add method to *Underscore, see func Range
+// A wrapped int is the first of start_stop_and_step, ie New(0).Range(10, 2)
+func (this *Underscore) Range(start_stop_and_step ...int) *Underscore {
+	if start, ok := this.wrapped.(int); ok {
+		start_stop_and_step = append([]int{start}, start_stop_and_step...)
 	}
-	return this
+	return this.result(Range(start_stop_and_step...))
+}
+
+// OOP-style support, add method to *Underscore, see func Result
+func (this *Underscore) Result(propertyName T) *Underscore {
+	return this.result(Result(this.wrapped, propertyName))
+}
+
+// OOP-style support, add method to *Underscore, see func IsOrderedMap
+func (this *Underscore) IsOrderedMap() *Underscore {
+	return this.result(IsOrderedMap(this.wrapped))
+}
+
+// OOP-style support, add method to *Underscore, see func MergeDeepWith
+func (this *Underscore) MergeDeepWith(strategy ArrayMergeStrategy, objs ...T) *Underscore {
+	return this.result(MergeDeepWith(strategy, append([]T{this.wrapped}, objs...)...))
+}
+
+// OOP-style support, add method to *Underscore, see func SetCopy
+// Aliased as Set
+func (this *Underscore) SetCopy(path T, value T) *Underscore {
+	return this.result(SetCopy(this.wrapped, path, value))
+}
+
+// OOP-style support, add method to *Underscore, see func UpdateCopy
+// Aliased as Update
+func (this *Underscore) UpdateCopy(path T, fn func(T) T) *Underscore {
+	return this.result(UpdateCopy(this.wrapped, path, fn))
+}
+
+// OOP-style support, add method to *Underscore, see func UnsetCopy
+// Aliased as Unset
+func (this *Underscore) UnsetCopy(path T) *Underscore {
+	return this.result(UnsetCopy(this.wrapped, path))
+}
+
+// OOP-style support, add method to *Underscore, see func JSONPointer
+// The wrapped value is the path
+func (this *Underscore) JSONPointer() *Underscore {
+	v, _ := this.wrapped.([]T)
+	return this.result(JSONPointer(v))
+}
+
+// OOP-style support, add method to *Underscore, see func JSONPatch
+// The wrapped value is the changes, ie New(a).Chain().Diff(b).JSONPatch()
+func (this *Underscore) JSONPatch() *Underscore {
+	v, _ := this.wrapped.([]Change)
+	return this.result(JSONPatch(v))
+}
+
+// OOP-style support, add method to *Underscore, see func Template
+// The wrapped value is the template text.  Panics if it doesn't compile, like template.Must
+func (this *Underscore) Template(opt_settings ...TemplateSettings) *Underscore {
+	text, _ := this.wrapped.(string)
+	render, err := Template(text, opt_settings...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return this.result(render)
+}
+
+// OOP-style support for the Function Functions.  The wrapped value is the function,
+// ie New(fn).Chain().Memoize().Value().(func(...T) T)
+
+// Internal function, the wrapped value as a func(...T) T, panics naming method if it isn't one
+func (this *Underscore) wrappedFunc(method string) func(...T) T {
+	fn, ok := this.wrapped.(func(...T) T)
+	if !ok {
+		panic(fmt.Sprintf("%s: the wrapped value is a %T, not a func(...T) T", method, this.wrapped))
+	}
+	return fn
+}
+
+// OOP-style support, add method to *Underscore, see func Partial
+func (this *Underscore) Partial(savedArgs ...T) *Underscore {
+	return this.result(Partial(this.wrappedFunc("Partial"), savedArgs...))
+}
+
+// OOP-style support, add method to *Underscore, see func PartialRight
+func (this *Underscore) PartialRight(savedArgs ...T) *Underscore {
+	return this.result(PartialRight(this.wrappedFunc("PartialRight"), savedArgs...))
+}
+
+// OOP-style support, add method to *Underscore, see func Curry
+func (this *Underscore) Curry() *Underscore {
+	return this.result(Curry(this.wrapped))
+}
+
+// OOP-style support, add method to *Underscore, see func CurryN
+func (this *Underscore) CurryN(n int) *Underscore {
+	return this.result(CurryN(this.wrappedFunc("CurryN"), n))
+}
+
+// OOP-style support, add method to *Underscore, see func Bind
+// The wrapped value is the object whose method is bound
+func (this *Underscore) Bind(methodName string, savedArgs ...T) *Underscore {
+	return this.result(Bind(this.wrapped, methodName, savedArgs...))
+}
+
+// OOP-style support, add method to *Underscore, see func BindAll
+// The wrapped value is the object whose methods are bound
+func (this *Underscore) BindAll(methodNames ...string) *Underscore {
+	return this.result(BindAll(this.wrapped, methodNames...))
+}
+
+// OOP-style support, add method to *Underscore, see func Memoize
+func (this *Underscore) Memoize(opt_hasher ...func(...T) T) *Underscore {
+	return this.result(Memoize(this.wrappedFunc("Memoize"), opt_hasher...))
+}
+
+// OOP-style support, add method to *Underscore, see func Delay
+// The result is the *Delayed handle
+func (this *Underscore) Delay(waitMilliseconds int64, args ...T) *Underscore {
+	return this.result(Delay(this.wrappedFunc("Delay"), waitMilliseconds, args...))
+}
+
+// OOP-style support, add method to *Underscore, see func DelayNano
+// The result is the *Delayed handle
+func (this *Underscore) DelayNano(waitNanoseconds int64, args ...T) *Underscore {
+	return this.result(DelayNano(this.wrappedFunc("DelayNano"), waitNanoseconds, args...))
+}
+
+// OOP-style support, add method to *Underscore, see func DelayAndWait
+// Deprecated: use Delay, then Result on the handle
+func (this *Underscore) DelayAndWait(waitMilliseconds int64, args ...T) *Underscore {
+	return this.result(DelayAndWait(this.wrappedFunc("DelayAndWait"), waitMilliseconds, args...))
+}
+
+// OOP-style support, add method to *Underscore, see func Defer
+// The result is the *Delayed handle
+func (this *Underscore) Defer(args ...T) *Underscore {
+	return this.result(Defer(this.wrappedFunc("Defer"), args...))
+}
+
+// OOP-style support, add method to *Underscore, see func Debounce
+// The wrapped value is a func() T
+func (this *Underscore) Debounce(waitMilliseconds int64, immediate ...bool) *Underscore {
+	fn, ok := this.wrapped.(func() T)
+	if !ok {
+		panic(fmt.Sprintf("Debounce: the wrapped value is a %T, not a func() T", this.wrapped))
+	}
+	return this.result(Debounce(fn, waitMilliseconds, immediate...))
+}
+
+// OOP-style support, add method to *Underscore, see func DebounceNano
+// The wrapped value is a func() T
+func (this *Underscore) DebounceNano(waitNanoseconds int64, optImmediate ...bool) *Underscore {
+	fn, ok := this.wrapped.(func() T)
+	if !ok {
+		panic(fmt.Sprintf("DebounceNano: the wrapped value is a %T, not a func() T", this.wrapped))
+	}
+	return this.result(DebounceNano(fn, waitNanoseconds, optImmediate...))
+}
+
+// OOP-style support, add method to *Underscore, see func Throttle
+func (this *Underscore) Throttle(waitMilliseconds int64, options ...map[string]bool) *Underscore {
+	return this.result(Throttle(this.wrappedFunc("Throttle"), waitMilliseconds, options...))
+}
+
+// OOP-style support, add method to *Underscore, see func ThrottleNano
+func (this *Underscore) ThrottleNano(waitN int64, options ...map[string]bool) *Underscore {
+	return this.result(ThrottleNano(this.wrappedFunc("ThrottleNano"), waitN, options...))
+}
+
+// OOP-style support, add method to *Underscore, see func Once
+func (this *Underscore) Once() *Underscore {
+	return this.result(Once(this.wrappedFunc("Once")))
+}
+
+// OOP-style support, add method to *Underscore, see func OnceWithError
+// The wrapped value is a func(...T) (T, error)
+func (this *Underscore) OnceWithError() *Underscore {
+	fn, ok := this.wrapped.(func(...T) (T, error))
+	if !ok {
+		panic(fmt.Sprintf("OnceWithError: the wrapped value is a %T, not a func(...T) (T, error)", this.wrapped))
+	}
+	return this.result(OnceWithError(fn))
+}
+
+// OOP-style support, add method to *Underscore, see func After
+func (this *Underscore) After(times int) *Underscore {
+	return this.result(After(times, this.wrappedFunc("After")))
+}
+
+// OOP-style support, add method to *Underscore, see func Before
+func (this *Underscore) Before(times int) *Underscore {
+	return this.result(Before(times, this.wrappedFunc("Before")))
+}
+
+// OOP-style support, add method to *Underscore, see func Limit
+func (this *Underscore) Limit(n int) *Underscore {
+	return this.result(Limit(this.wrappedFunc("Limit"), n))
+}
+
+// OOP-style support, add method to *Underscore, see func RateLimit
+func (this *Underscore) RateLimit(ratePerSecond float64, burst int) *Underscore {
+	return this.result(RateLimit(this.wrappedFunc("RateLimit"), ratePerSecond, burst))
+}
+
+// OOP-style support, add method to *Underscore, see func Retry
+// The wrapped value is a func(...T) (T, error)
+func (this *Underscore) Retry(policy RetryPolicy) *Underscore {
+	fn, ok := this.wrapped.(func(...T) (T, error))
+	if !ok {
+		panic(fmt.Sprintf("Retry: the wrapped value is a %T, not a func(...T) (T, error)", this.wrapped))
+	}
+	return this.result(Retry(fn, policy))
+}
+
+// OOP-style support, add method to *Underscore, see func Wrap
+func (this *Underscore) Wrap(wrapper func(...T) T) *Underscore {
+	return this.result(Wrap(this.wrappedFunc("Wrap"), wrapper))
+}
+
+// OOP-style support, add method to *Underscore, see func Compose
+// The wrapped function is the first of funcs, so it's called last
+func (this *Underscore) Compose(funcs ...T) *Underscore {
+	return this.result(Compose(append([]T{this.wrapped}, funcs...)...))
+}
+
+// OOP-style support, add method to *Underscore, see func Flow
+// The wrapped function is the first of funcs.  Panics if they can't be piped together, as Compose does
+func (this *Underscore) Flow(funcs ...T) *Underscore {
+	flow, err := Flow(append([]T{this.wrapped}, funcs...)...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return this.result(flow)
+}
+
+// OOP-style support, add method to *Underscore, see func FlowRight
+// The wrapped function is the first of funcs, so it's called last.  Panics if they can't be piped together, as Compose does
+func (this *Underscore) FlowRight(funcs ...T) *Underscore {
+	flow, err := FlowRight(append([]T{this.wrapped}, funcs...)...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return this.result(flow)
+}
+
+// Helper function to continue with intermediate results, wrapped in a new *Underscore
+// that's chained if this one is.  The receiver is left as it was.
+func (this *Underscore) result(obj T) *Underscore {
+	return &Underscore{ischained: this.ischained, wrapped: obj}
 }
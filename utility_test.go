@@ -4,7 +4,11 @@ import (
 	"github.com/markmontymark/asserts"
 	"fmt"
 	"math"
+	"regexp"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 // TODO: missing Underscore ctor tests
@@ -28,7 +32,50 @@ func TestRandom(t *testing.T) {
 		}))
 }
 
-// TODO: missing UniqueId tests
+func TestUniqueId(t *testing.T) {
+	UniqueIds.Reset()
+	asserts.Equals(t, "counts up from 1", UniqueId(), "1")
+	asserts.Equals(t, "can use a prefix", UniqueId("contact_"), "contact_2")
+	asserts.Equals(t, "chain uses the wrapped prefix", New("x").Chain().UniqueId().Value().(string), "x3")
+	UniqueIds.Reset()
+	asserts.Equals(t, "can be reset", UniqueId("a"), "a1")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				id := UniqueId()
+				mu.Lock()
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	asserts.IntEquals(t, "ids are unique across goroutines", len(seen), 1000)
+}
+
+func TestIdGenerator(t *testing.T) {
+	times := NewIdGenerator(new(TimeIds))
+	first, _ := strconv.ParseInt(times.Next(), 10, 64)
+	second, _ := strconv.ParseInt(times.Next(), 10, 64)
+	asserts.True(t, "time ids always increase", second > first && first > 0)
+
+	uuids := NewIdGenerator(new(RandomIds))
+	id := uuids.Next("u-")
+	asserts.True(t, "random ids look like v4 UUIDs",
+		regexp.MustCompile(`^u-[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id))
+	asserts.True(t, "random ids differ", uuids.Next() != uuids.Next())
+
+	counter := NewIdGenerator(new(CounterIds))
+	counter.Next()
+	counter.Reset()
+	asserts.Equals(t, "each generator counts on its own", counter.Next("c"), "c1")
+}
 
 func TestTimes(t *testing.T) {
 	vals := []T{}
@@ -58,8 +105,146 @@ func TestTimes(t *testing.T) {
 		"[]")
 }
 
-// XXX: missing mixin tests -- might not do...no prototype to add to in Go
-// XXX: missing escape,unescape tests -- might not do...Go and encoding/ package
-// XXX: missing template tests -- might not do...Go has its own template package
+func TestMixin(t *testing.T) {
+	err := Mixin("myReverse", func(obj T, args ...T) T {
+		reversed := ""
+		for _, c := range obj.(string) {
+			reversed = string(c) + reversed
+		}
+		return reversed
+	})
+	asserts.Nil(t, "mixes in a function", err)
+	asserts.Equals(t, "calls a mixed in function", New("panacea").Chain().Call("myReverse").Value().(string), "aecanap")
+
+	Mixin("times", func(obj T, args ...T) T { return obj.(int) * args[0].(int) })
+	asserts.IntEquals(t, "passes args and keeps chaining",
+		New(3).Chain().Call("times", 4).Pipe(func(v T) T { return v.(int) + 1 }).Value().(int), 13)
+
+	Mixin("times", func(obj T, args ...T) T { return 0 })
+	asserts.IntEquals(t, "mixing in again replaces", New(3).Chain().Call("times", 4).Value().(int), 0)
+
+	asserts.True(t, "refuses built in names", Mixin("Map", func(obj T, args ...T) T { return obj }) != nil)
+	asserts.True(t, "refuses nil functions", Mixin("nothing", nil) != nil)
+
+	defer func() {
+		asserts.True(t, "calling an unknown mixin panics", recover() != nil)
+	}()
+	New(1).Call("neverMixedIn")
+}
+func TestEscape(t *testing.T) {
+	asserts.Equals(t, "escapes html", Escape("Curly & Moe").(string), "Curly &amp; Moe")
+	asserts.Equals(t, "escapes every entity", Escape("<a href=\"x\">'`&</a>").(string),
+		"&lt;a href=&quot;x&quot;&gt;&#x27;&#x60;&amp;&lt;/a&gt;")
+	asserts.Equals(t, "double escapes", Escape("Curly &amp; Moe").(string), "Curly &amp;amp; Moe")
+	asserts.Nil(t, "leaves nil alone", Escape(nil))
+	asserts.Equals(t, "escapes nested values", fmt.Sprint(Escape([]T{"<", map[T]T{"k": []T{"&"}}, 1})), "[&lt; map[k:[&amp;]] 1]")
+	asserts.Equals(t, "escapes OrderedMap values", fmt.Sprint(Escape(NewOrderedMap("<", ">"))), "map[<:&gt;]")
+	asserts.Equals(t, "chain", New("a<b").Chain().Escape().Value().(string), "a&lt;b")
+}
+
+func TestUnescape(t *testing.T) {
+	asserts.Equals(t, "unescapes html", Unescape("Curly &amp; Moe").(string), "Curly & Moe")
+	asserts.Equals(t, "unescapes every entity", Unescape("&lt;&gt;&quot;&#x27;&#x60;&amp;").(string), "<>\"'`&")
+	asserts.Equals(t, "unescapes once", Unescape("&amp;amp;").(string), "&amp;")
+	asserts.Equals(t, "round trips", Unescape(Escape("<b>'`\"&</b>")).(string), "<b>'`\"&</b>")
+	asserts.Equals(t, "unescapes nested values", fmt.Sprint(Unescape(map[T]T{"k": []T{"&lt;"}})), "map[k:[<]]")
+	asserts.Equals(t, "chain", New("a&lt;b").Chain().Unescape().Value().(string), "a<b")
+}
+// A named string type, which templates convert strings to when calling functions
+type templateLabel string
+
+type templatePerson struct {
+	Name string
+	Age  int
+}
+
+func TestTemplate(t *testing.T) {
+	basic, _ := Template("<p>\"<%= thing %>\" is gettin' on my noives!</p>")
+	result, _ := basic(map[T]T{"thing": "This"})
+	asserts.Equals(t, "can do basic attribute interpolation", result, "<p>\"This\" is gettin' on my noives!</p>")
+
+	sansSemicolon, _ := Template("A <% this %> B")
+	result, _ = sansSemicolon(map[T]T{})
+	asserts.Equals(t, "evaluates code without a semicolon", result, "A  B")
+
+	backslash, _ := Template("<%= thing %> is \\ridanculous")
+	result, _ = backslash(map[T]T{"thing": "This"})
+	asserts.Equals(t, "keeps backslashes", result, "This is \\ridanculous")
+
+	fancy, err := Template("<ul><% for (var key in people) { %><li><%= people[key] %></li><% } %></ul>")
+	asserts.Nil(t, "compiles for-in", err)
+	result, _ = fancy(map[T]T{"people": map[T]T{"moe": "Moe", "larry": "Larry", "curly": "Curly"}})
+	asserts.Equals(t, "can run arbitrary javascript in templates", result, "<ul><li>Curly</li><li>Larry</li><li>Moe</li></ul>")
+
+	each, _ := Template("<ul><% _.each(people, function(p, i) { %><li><%= i + 1 %>. <%- p.name %></li><% }); %></ul>")
+	result, _ = each(map[T]T{"people": []T{templatePerson{"Moe", 40}, &templatePerson{"<b>'Larry'</b>", 41}}})
+	asserts.Equals(t, "each with a function, struct fields and escaping", result,
+		"<ul><li>1. Moe</li><li>2. &lt;b&gt;&#x27;Larry&#x27;&lt;/b&gt;</li></ul>")
+
+	ifElse, _ := Template("<% if (age >= 21 && !banned) { %>drink<% } else if (age > 17) { %>vote<% } else { %>wait<% } %>")
+	for age, expected := range map[int]string{30: "drink", 18: "vote", 5: "wait"} {
+		result, _ = ifElse(map[T]T{"age": age, "banned": false})
+		asserts.Equals(t, "if else if else", result, expected)
+	}
+
+	loops, _ := Template("<% var total = 0; for (var i = 0; i < list.length; i++) { total += list[i]; } print('sum ' + total); %>" +
+		"<%= list.length > 2 ? 'long' : 'short' %> <%= _.size(list) %> <%= _.map(list, function(n) { return n * 2; }) %>")
+	result, _ = loops(map[T]T{"list": []T{1, 2, 3}})
+	asserts.Equals(t, "for loops, print, ternaries, helpers and return", result, "sum 6long 3 2,4,6")
+
+	nulls, _ := Template("[<%= missing %>][<%- nothing %>][<%= 7 / 2 %>][<%= 'a' + 1 %>]")
+	result, _ = nulls(map[T]T{"nothing": nil})
+	asserts.Equals(t, "nil interpolates as empty, JavaScript arithmetic", result, "[][][3.5][a1]")
+
+	ordered, _ := Template("<% for (var k in o) { %><%= k %>=<%= o[k] %> <% } %>")
+	result, _ = ordered(map[T]T{"o": NewOrderedMap("z", 1, "a", 2)})
+	asserts.Equals(t, "OrderedMaps loop in order", result, "z=1 a=2 ")
+
+	mustache, _ := Template("Hello {{ planet }}!{{- tag }}{% print(1) %}", TemplateSettings{
+		Interpolate: regexp.MustCompile(`\{\{([\s\S]+?)\}\}`),
+		Escape:      regexp.MustCompile(`\{\{-([\s\S]+?)\}\}`),
+		Evaluate:    regexp.MustCompile(`\{%([\s\S]+?)%\}`),
+	})
+	result, _ = mustache(map[T]T{"planet": "World", "tag": "<i>"})
+	asserts.Equals(t, "can use custom settings", result, "Hello World!&lt;i&gt;1")
+
+	variable, _ := Template("<%= data.x %>,<%= x %>", TemplateSettings{Variable: "data"})
+	result, _ = variable(map[T]T{"x": 1})
+	asserts.Equals(t, "variable setting", result, "1,")
+
+	helper, _ := Template("<%= shout(name) %>")
+	result, _ = helper(map[T]T{"name": "moe", "shout": func(s string) string { return s + "!" }})
+	asserts.Equals(t, "calls Go functions from the data", result, "moe!")
+
+	converting, _ := Template("<%= label(name) %> <%= wait(2) %> <%= half(7) %>")
+	result, _ = converting(map[T]T{"name": "moe",
+		"label": func(n templateLabel) string { return "[" + string(n) + "]" },
+		"wait":  func(d time.Duration) string { return d.String() },
+		"half":  func(n float32) float32 { return n / 2 }})
+	asserts.Equals(t, "converts arguments to named and numeric parameter types", result, "[moe] 2ns 3.5")
+	stringly, _ := Template("<%= f(5) %>")
+	_, err = stringly(map[T]T{"f": func(s string) string { return "[" + s + "]" }})
+	asserts.True(t, "but doesn't pass numbers as strings", err != nil)
+
+	_, err = Template("<% if (x { %>")
+	asserts.True(t, "reports compile errors", err != nil)
+	_, err = Template("<% _.each(list, function(x) { %>")
+	asserts.True(t, "reports unclosed blocks", err != nil)
+	broken, _ := Template("<%= notAFunc() %>")
+	_, err = broken(map[T]T{})
+	asserts.True(t, "reports render errors", err != nil)
+
+	unicodeNames, _ := Template("<% var café = 'crème'; %><%= café %> <%= naïve %> <%= 'déjà vu' %>")
+	result, err = unicodeNames(map[T]T{"naïve": "ü"})
+	asserts.Nil(t, "lexes non-ASCII identifiers", err)
+	asserts.Equals(t, "and reads them, and strings, whole", result, "crème ü déjà vu")
+
+	forever, _ := Template("<% for (;;) { } %>")
+	_, err = forever(map[T]T{})
+	asserts.True(t, "stops endless loops with an error", err != nil)
+	recursive, _ := Template("<% var f = function() { return f(); }; f(); %>")
+	_, err = recursive(map[T]T{})
+	asserts.True(t, "stops endless recursion with an error", err != nil)
+}
 
 // TODO: missing result tests
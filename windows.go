@@ -0,0 +1,226 @@
+package underscore
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// A stream element with the time it happened, for StreamWindow
+type Timestamped struct {
+	Time  time.Time
+	Value T
+}
+
+// How StreamWindow divides a stream up.  Set Size for tumbling windows, Size and Slide for
+// sliding windows, or Gap for session windows.
+type WindowOptions struct {
+	// The length of each window
+	Size time.Duration
+	// How far apart sliding windows start, defaults to Size, giving tumbling windows.
+	// With Slide above Size, the windows are spaced apart and elements between them are dropped
+	Slide time.Duration
+	// For session windows, which close once Gap passes without an element
+	Gap time.Duration
+	// How far behind the latest element seen an element may be and still count.
+	// A window is closed, and passed on, once the watermark (the latest time seen less
+	// AllowedLateness) reaches its end
+	AllowedLateness time.Duration
+	// Read an element's time, defaults to using Timestamped elements' Time, or
+	// Clock's time for any other element
+	Time func(elem T) time.Time
+	// Stamps elements that aren't Timestamped when Time isn't set, defaults to DefaultClock
+	Clock Clock
+	// Called with elements that arrive after all of their windows have closed, which are otherwise dropped.
+	// Elements between windows spaced apart by Slide aren't late, so aren't passed to it
+	OnLate func(elem T)
+}
+
+// A closed window, passed on by StreamWindow
+type Window struct {
+	// The window covers times from Start, up to but not including End
+	Start time.Time
+	End   time.Time
+	// The number of elements in the window
+	Count int
+	// The aggregate of the window's elements
+	Result T
+}
+
+// Divide a stream into time windows and pass on an aggregate of each window's elements
+// as it closes, ie per-minute counts: StreamWindow(ctx, events, WindowOptions{Size: time.Minute}, WindowCountBy("status"))
+// The aggregate is given the values of the window's elements (the Value of Timestamped elements),
+// in the order they arrived; a nil aggregate makes the Result that list.
+// Fixed windows are aligned to multiples of Slide (or Size) since the zero time, so minute windows start on the minute.
+// Windows still open when the input closes are passed on then, in order of their ends.
+// Panics if options has neither a Size nor a Gap above 0, as there'd be no windows.
+func StreamWindow(ctx context.Context, in <-chan T, options WindowOptions, aggregate func(values []T) T) <-chan Window {
+	if options.Size <= 0 && options.Gap <= 0 {
+		panic("StreamWindow: WindowOptions needs a Size or Gap above 0")
+	}
+	if aggregate == nil {
+		aggregate = func(values []T) T { return values }
+	}
+	out := make(chan Window)
+	go func() {
+		defer close(out)
+		var open []*Window
+		values := make(map[*Window][]T)
+		var watermark time.Time
+		seen := false
+		// pass on the windows ending by the watermark, or all of them
+		emit := func(all bool) bool {
+			sort.SliceStable(open, func(i, j int) bool {
+				if open[i].End.Equal(open[j].End) {
+					return open[i].Start.Before(open[j].Start)
+				}
+				return open[i].End.Before(open[j].End)
+			})
+			for len(open) > 0 && (all || !open[0].End.After(watermark)) {
+				window := open[0]
+				open = open[1:]
+				window.Count = len(values[window])
+				window.Result = aggregate(values[window])
+				delete(values, window)
+				select {
+				case out <- *window:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+		for {
+			var elem T
+			select {
+			case value, ok := <-in:
+				if !ok {
+					emit(true)
+					return
+				}
+				elem = value
+			case <-ctx.Done():
+				return
+			}
+			at, value := windowStamp(options, elem)
+			if options.Gap > 0 {
+				if seen && !at.Add(options.Gap).After(watermark) {
+					if options.OnLate != nil {
+						options.OnLate(elem)
+					}
+					continue
+				}
+				open = addToSession(open, values, at, value, options.Gap)
+			} else {
+				windows := fixedWindows(at, options)
+				added := len(windows) == 0
+				for _, window := range windows {
+					if seen && !window.End.After(watermark) {
+						continue
+					}
+					found := false
+					for _, existing := range open {
+						if existing.Start.Equal(window.Start) {
+							values[existing] = append(values[existing], value)
+							found = true
+							break
+						}
+					}
+					if !found {
+						open = append(open, window)
+						values[window] = []T{value}
+					}
+					added = true
+				}
+				if !added {
+					// every window it fell in has closed, rather than it falling between windows
+					if options.OnLate != nil {
+						options.OnLate(elem)
+					}
+					continue
+				}
+			}
+			if mark := at.Add(-options.AllowedLateness); !seen || mark.After(watermark) {
+				watermark, seen = mark, true
+			}
+			if !emit(false) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Internal function, an element's time and the value to aggregate
+func windowStamp(options WindowOptions, elem T) (time.Time, T) {
+	if options.Time != nil {
+		return options.Time(elem), elem
+	}
+	if stamped, ok := elem.(Timestamped); ok {
+		return stamped.Time, stamped.Value
+	}
+	return clockOrDefault(options.Clock).Now(), elem
+}
+
+// Internal function, the tumbling or sliding windows a time falls in, latest first
+func fixedWindows(at time.Time, options WindowOptions) []*Window {
+	slide := options.Slide
+	if slide <= 0 {
+		slide = options.Size
+	}
+	windows := make([]*Window, 0)
+	for start := at.Truncate(slide); start.Add(options.Size).After(at); start = start.Add(-slide) {
+		windows = append(windows, &Window{Start: start, End: start.Add(options.Size)})
+	}
+	return windows
+}
+
+// Internal function to add a value to the session windows, merging any sessions it bridges
+func addToSession(open []*Window, values map[*Window][]T, at time.Time, value T, gap time.Duration) []*Window {
+	session := &Window{Start: at, End: at.Add(gap)}
+	merged := []T{}
+	kept := open[:0]
+	for _, window := range open {
+		if window.Start.Before(session.End) && at.Before(window.End) {
+			if window.Start.Before(session.Start) {
+				session.Start = window.Start
+			}
+			if window.End.After(session.End) {
+				session.End = window.End
+			}
+			merged = append(merged, values[window]...)
+			delete(values, window)
+		} else {
+			kept = append(kept, window)
+		}
+	}
+	values[session] = append(merged, value)
+	return append(kept, session)
+}
+
+// An aggregate for StreamWindow counting the window's values by a criterion, see func CountBy
+func WindowCountBy(iterator T) func(values []T) T {
+	return func(values []T) T {
+		return CountBy(values, iterator)
+	}
+}
+
+// An aggregate for StreamWindow grouping the window's values by a criterion, see func GroupBy
+func WindowGroupBy(iterator T) func(values []T) T {
+	return func(values []T) T {
+		return GroupBy(values, iterator)
+	}
+}
+
+// An aggregate for StreamWindow reducing the window's values, see func Reduce.
+// Each window starts from its own CloneDeep of memo, so a map or slice memo isn't shared between windows
+func WindowReduce(iterator func(T, T, T, T) T, memo ...T) func(values []T) T {
+	return func(values []T) T {
+		windowMemo := make([]T, len(memo))
+		for i, m := range memo {
+			windowMemo[i] = CloneDeep(m)
+		}
+		result, _ := Reduce(values, iterator, windowMemo...)
+		return result
+	}
+}